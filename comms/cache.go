@@ -0,0 +1,176 @@
+package comms
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/marshal"
+	"github.com/vision-cli/common/plugins"
+)
+
+// cacheDirName is the directory under the user cache dir that NewCache stores responses in.
+const cacheDirName = "vision/plugins"
+
+// Cache stores plugin responses on disk so that calls which are pure can skip running the plugin.
+// A response is keyed on the checksum of the plugin binary and the encoded request, so it is no
+// longer used once the plugin is rebuilt or upgraded. Only successful responses from external
+// plugins are stored.
+type Cache struct {
+	Dir string
+	TTL time.Duration // how long a response is used for, forever if zero
+}
+
+// NewCache returns a cache under the user cache directory that keeps responses for ttl.
+func NewCache(ttl time.Duration) (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("cannot find plugin cache directory: %w", err)
+	}
+	return &Cache{Dir: filepath.Join(dir, filepath.FromSlash(cacheDirName)), TTL: ttl}, nil
+}
+
+// cacheEntry is the file stored for one response.
+type cacheEntry struct {
+	Checksum string
+	Stored   time.Time
+	Response string
+}
+
+// Clear removes every stored response.
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.Dir)
+}
+
+// Invalidate removes the stored responses of plugin.
+func (c *Cache) Invalidate(plugin plugins.Plugin) error {
+	return os.RemoveAll(c.pluginDir(plugin))
+}
+
+// get returns the response stored under key, if it is still fresh.
+func (c *Cache) get(plugin plugins.Plugin, key string) (string, bool) {
+	data, err := os.ReadFile(c.path(plugin, key))
+	if err != nil {
+		return "", false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || c.expired(entry) {
+		return "", false
+	}
+	return entry.Response, true
+}
+
+// put stores response under key and removes responses from other builds of plugin.
+func (c *Cache) put(plugin plugins.Plugin, checksum string, key string, response string) error {
+	dir := c.pluginDir(plugin)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	c.prune(dir, checksum)
+	data, err := json.Marshal(cacheEntry{Checksum: checksum, Stored: time.Now(), Response: response})
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so concurrent invocations never read a partial entry
+	tmp, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(plugin, key))
+}
+
+// prune removes entries in dir that are expired or belong to a binary other than checksum.
+func (c *Cache) prune(dir string, checksum string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var entry cacheEntry
+		if json.Unmarshal(data, &entry) != nil || entry.Checksum != checksum || c.expired(entry) {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+func (c *Cache) expired(entry cacheEntry) bool {
+	return c.TTL > 0 && time.Now().Sub(entry.Stored) > c.TTL
+}
+
+func (c *Cache) pluginDir(plugin plugins.Plugin) string {
+	return filepath.Join(c.Dir, plugin.Name)
+}
+
+func (c *Cache) path(plugin plugins.Plugin, key string) string {
+	return filepath.Join(c.pluginDir(plugin), key+".json")
+}
+
+// cacheKey returns the key of query sent to the plugin binary with checksum.
+func cacheKey(checksum string, query string) string {
+	h := sha256.New()
+	h.Write([]byte(checksum))
+	h.Write([]byte{0})
+	h.Write([]byte(query))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cached runs call unless the cache in o holds a response for query, and stores its response
+// when it decodes successfully. Internal plugins, and plugins whose binary cannot be read, are
+// never cached.
+func cached[T any](plugin plugins.Plugin, query string, o *callOptions, call func() (string, error)) (*T, error) {
+	var checksum string
+	if o.cache != nil && plugin.InternalCommand == nil {
+		checksum, _ = plugins.Checksum(plugin.PluginPath)
+	}
+	if checksum == "" {
+		response, err := call()
+		if err != nil {
+			return nil, err
+		}
+		return decodeResponse[T](plugin, response, o.codec())
+	}
+
+	key := cacheKey(checksum, query)
+	if response, ok := o.cache.get(plugin, key); ok {
+		if out, err := decodeResponse[T](plugin, response, o.codec()); err == nil {
+			return out, nil
+		}
+	}
+	response, err := call()
+	if err != nil {
+		return nil, err
+	}
+	out, err := decodeResponse[T](plugin, response, o.codec())
+	if err != nil {
+		return nil, err
+	}
+	if failed, err := marshal.UnmarshalWith[api_v1.PluginResponse](o.codec(), response); err == nil && failed.Error != "" {
+		return out, nil
+	}
+	// a cache that cannot be written only costs speed, so the response is still returned
+	_ = o.cache.put(plugin, checksum, key, response)
+	return out, nil
+}
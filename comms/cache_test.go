@@ -0,0 +1,112 @@
+package comms_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/comms"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/plugins"
+)
+
+// countingPlugin writes a plugin that records each run in a file and answers with msg,
+// or with an error response when msg is empty.
+func countingPlugin(t *testing.T, dir string, msg string) (plugins.Plugin, func() int) {
+	t.Helper()
+	runs := filepath.Join(dir, "runs")
+	path := filepath.Join(dir, "vision-plugin-cached-v1")
+	response := `{"Msg":"` + msg + `"}`
+	if msg == "" {
+		response = `{"Result":"","Error":"failed"}`
+	}
+	script := "#!/bin/sh\ncat > /dev/null\necho run >> " + runs + "\necho '" + response + "'\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	count := func() int {
+		data, _ := os.ReadFile(runs)
+		return strings.Count(string(data), "run")
+	}
+	return plugins.Plugin{Name: "vision-plugin-cached-v1", PluginPath: path}, count
+}
+
+func TestCallContext_WithCache_RunsPluginOnce(t *testing.T) {
+	plugin, runs := countingPlugin(t, t.TempDir(), "hello")
+	cache := &comms.Cache{Dir: t.TempDir()}
+
+	for i := 0; i < 3; i++ {
+		result, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithCache(cache))
+		require.NoError(t, err)
+		assert.Equal(t, "hello", result.Msg)
+	}
+	assert.Equal(t, 1, runs())
+}
+
+func TestCallContext_WithCache_KeysOnRequest(t *testing.T) {
+	plugin, runs := countingPlugin(t, t.TempDir(), "hello")
+	cache := &comms.Cache{Dir: t.TempDir()}
+	other := api_v1.PluginRequest{Command: api_v1.CommandRun, Args: []string{"other"}}
+
+	_, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithCache(cache))
+	require.NoError(t, err)
+	_, err = comms.Call[TestMsg](plugin, &other, execute.NewOsExecutor(), comms.WithCache(cache))
+	require.NoError(t, err)
+	assert.Equal(t, 2, runs())
+}
+
+func TestCallContext_WithCache_WhenPluginChanges_RunsPluginAgain(t *testing.T) {
+	dir := t.TempDir()
+	plugin, runs := countingPlugin(t, dir, "old")
+	cache := &comms.Cache{Dir: t.TempDir()}
+
+	_, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithCache(cache))
+	require.NoError(t, err)
+	countingPlugin(t, dir, "new")
+	result, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithCache(cache))
+	require.NoError(t, err)
+	assert.Equal(t, "new", result.Msg)
+	assert.Equal(t, 2, runs())
+
+	entries, err := os.ReadDir(filepath.Join(cache.Dir, plugin.Name))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "responses from the old binary should be removed")
+}
+
+func TestCallContext_WithCache_WhenExpired_RunsPluginAgain(t *testing.T) {
+	plugin, runs := countingPlugin(t, t.TempDir(), "hello")
+	cache := &comms.Cache{Dir: t.TempDir(), TTL: 50 * time.Millisecond}
+
+	_, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithCache(cache))
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, err = comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithCache(cache))
+	require.NoError(t, err)
+	assert.Equal(t, 2, runs())
+}
+
+func TestCallContext_WithCache_DoesNotStoreErrors(t *testing.T) {
+	plugin, runs := countingPlugin(t, t.TempDir(), "")
+	cache := &comms.Cache{Dir: t.TempDir()}
+
+	for i := 0; i < 2; i++ {
+		_, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithCache(cache))
+		assert.EqualError(t, err, "failed")
+	}
+	assert.Equal(t, 2, runs())
+}
+
+func TestCache_Invalidate_RemovesPluginResponses(t *testing.T) {
+	plugin, runs := countingPlugin(t, t.TempDir(), "hello")
+	cache := &comms.Cache{Dir: t.TempDir()}
+
+	_, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithCache(cache))
+	require.NoError(t, err)
+	require.NoError(t, cache.Invalidate(plugin))
+	_, err = comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithCache(cache))
+	require.NoError(t, err)
+	assert.Equal(t, 2, runs())
+}
@@ -1,6 +1,8 @@
 package comms
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -12,36 +14,128 @@ import (
 	"github.com/vision-cli/common/tmpl"
 )
 
-func Call[T any](plugin plugins.Plugin, request *api_v1.PluginRequest, executor execute.Executor) (*T, error) {
+// TimeoutError is returned when the context of a call is cancelled or its deadline passes
+// before the plugin responds. Use errors.As to tell it apart from a failing plugin.
+type TimeoutError struct {
+	Plugin string
+	Err    error // the context error, either context.Canceled or context.DeadlineExceeded
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("plugin %s did not respond: %s", e.Plugin, e.Err.Error())
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Call sends request to plugin and decodes the response into T.
+func Call[T any](plugin plugins.Plugin, request *api_v1.PluginRequest, executor execute.Executor, opts ...CallOption) (*T, error) {
+	return CallContext[T](context.Background(), plugin, request, executor, opts...)
+}
+
+// CallContext is like Call but stops waiting for the plugin when ctx is done.
+// External plugins are killed along with any processes they started.
+func CallContext[T any](ctx context.Context, plugin plugins.Plugin, request *api_v1.PluginRequest, executor execute.Executor, opts ...CallOption) (*T, error) {
 	if request == nil {
 		return nil, fmt.Errorf("comms.Call called with nil request")
 	}
 	if executor == nil {
 		return nil, fmt.Errorf("comms.Call called with nil executor")
 	}
-	cmd := exec.Command(plugin.PluginPath)
-	query, err := marshal.Marshal(request)
+	o := newCallOptions(opts)
+	query, err := o.encode(request)
 	if err != nil {
 		return nil, fmt.Errorf("cannot marshal request for plugin %s: %s", plugin.Name, err.Error())
 	}
-	cmd.Stdin = strings.NewReader(query)
 
-	var response string
-	if plugin.InternalCommand == nil {
-		execResponse, err := executor.Output(cmd, ".", "calling plugin "+plugin.Name)
-		if err != nil {
-			return nil, fmt.Errorf("cannot run plugin %s", plugin.Name)
+	return cached[T](plugin, query, o, func() (string, error) {
+		return send(ctx, plugin, request.Command, query, executor, o)
+	})
+}
+
+// send delivers query to plugin and returns its raw response.
+// command is the request command, recorded in any PluginError.
+// env is added to the environment of external plugins.
+func send(ctx context.Context, plugin plugins.Plugin, command string, query string, executor execute.Executor, o *callOptions, env ...string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", &TimeoutError{Plugin: plugin.Name, Err: err}
+	}
+	if plugin.InternalCommand != nil {
+		return sendInternal(ctx, plugin, query, executor)
+	}
+
+	if err := o.verify(plugin); err != nil {
+		return "", err
+	}
+	cmd, err := newPluginCmd(ctx, plugin, query, o, env...)
+	if err != nil {
+		return "", err
+	}
+	stderr := newTailBuffer(maxStderrTail)
+	cmd.Stderr = stderr
+	response, err := executor.Output(cmd, o.dir(), "calling plugin "+plugin.Name)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", &TimeoutError{Plugin: plugin.Name, Err: ctx.Err()}
 		}
-		response = execResponse
-	} else {
-		templateWriter := tmpl.NewOsTmpWriter()
-		response = plugin.InternalCommand(query, executor, templateWriter)
+		return "", newPluginError(plugin, command, stderr.String(), err)
+	}
+	return response, nil
+}
+
+// newPluginCmd returns the command that runs an external plugin with query on its stdin
+// and env added to its environment.
+func newPluginCmd(ctx context.Context, plugin plugins.Plugin, query string, o *callOptions, env ...string) (*exec.Cmd, error) {
+	cmd := execute.CommandContext(ctx, plugin.PluginPath)
+	cmd.Stdin = strings.NewReader(query)
+	if err := o.prepare(cmd, env...); err != nil {
+		return nil, fmt.Errorf("cannot run plugin %s: %w", plugin.Name, err)
 	}
+	return cmd, nil
+}
 
-	out, err := marshal.Unmarshal[T](response)
+func newPluginError(plugin plugins.Plugin, command string, stderr string, err error) *PluginError {
+	pluginErr := &PluginError{
+		Plugin:   plugin.Name,
+		Path:     plugin.PluginPath,
+		Command:  command,
+		ExitCode: -1,
+		Stderr:   stderr,
+		Err:      err,
+	}
+	// *exec.ExitError and errors from executors that replay commands report the exit code
+	var exited interface{ ExitCode() int }
+	if errors.As(err, &exited) {
+		pluginErr.ExitCode = exited.ExitCode()
+	}
+	var exitErr *exec.ExitError
+	if pluginErr.Stderr == "" && errors.As(err, &exitErr) {
+		pluginErr.Stderr = string(exitErr.Stderr)
+	}
+	return pluginErr
+}
+
+// sendInternal runs an in-process plugin. The plugin cannot be interrupted, so when ctx is done
+// first its eventual response is discarded and any further commands it runs are refused.
+func sendInternal(ctx context.Context, plugin plugins.Plugin, query string, executor execute.Executor) (string, error) {
+	done := make(chan string, 1)
+	go func() {
+		done <- plugin.InternalCommand(query, contextExecutor{ctx: ctx, Executor: executor}, tmpl.NewOsTmpWriter())
+	}()
+	select {
+	case response := <-done:
+		return response, nil
+	case <-ctx.Done():
+		return "", &TimeoutError{Plugin: plugin.Name, Err: ctx.Err()}
+	}
+}
+
+func decodeResponse[T any](plugin plugins.Plugin, response string, codec marshal.Codec) (*T, error) {
+	out, err := marshal.UnmarshalWith[T](codec, response)
 	if err != nil {
 		// check if the response is an error
-		outerr, err := marshal.Unmarshal[api_v1.PluginResponse](response)
+		outerr, err := marshal.UnmarshalWith[api_v1.PluginResponse](codec, response)
 		if err != nil {
 			return nil, fmt.Errorf("cannot unmarshal response from plugin %s: %s", plugin.Name, err.Error())
 		}
@@ -53,3 +147,71 @@ func Call[T any](plugin plugins.Plugin, request *api_v1.PluginRequest, executor
 	}
 	return &out, nil
 }
+
+// contextExecutor stops commands when ctx is done. Commands that have started are stopped if
+// the wrapped executor is an execute.ExecutorContext; otherwise only new commands are refused.
+type contextExecutor struct {
+	ctx context.Context
+	execute.Executor
+}
+
+func (e contextExecutor) Errors(cmd *exec.Cmd, targetDir string, action string) error {
+	return e.ErrorsContext(context.Background(), cmd, targetDir, action)
+}
+
+func (e contextExecutor) ErrorsContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) error {
+	ctx, stop := e.merge(ctx)
+	defer stop()
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	if inner, ok := e.Executor.(execute.ExecutorContext); ok {
+		return inner.ErrorsContext(ctx, cmd, targetDir, action)
+	}
+	return e.Executor.Errors(cmd, targetDir, action)
+}
+
+func (e contextExecutor) Run(cmd *exec.Cmd, targetDir string, action string) (execute.Result, error) {
+	return e.RunContext(context.Background(), cmd, targetDir, action)
+}
+
+func (e contextExecutor) RunContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) (execute.Result, error) {
+	ctx, stop := e.merge(ctx)
+	defer stop()
+	if err := ctx.Err(); err != nil {
+		return execute.Result{Command: cmd.String(), ExitCode: -1}, fmt.Errorf("%s: %w", action, err)
+	}
+	if inner, ok := e.Executor.(execute.ExecutorContext); ok {
+		return inner.RunContext(ctx, cmd, targetDir, action)
+	}
+	return execute.RunWith(e.Executor, cmd, targetDir, action)
+}
+
+func (e contextExecutor) Output(cmd *exec.Cmd, targetDir string, action string) (string, error) {
+	return e.OutputContext(context.Background(), cmd, targetDir, action)
+}
+
+func (e contextExecutor) OutputContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) (string, error) {
+	ctx, stop := e.merge(ctx)
+	defer stop()
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("%s: %w", action, err)
+	}
+	if inner, ok := e.Executor.(execute.ExecutorContext); ok {
+		return inner.OutputContext(ctx, cmd, targetDir, action)
+	}
+	return e.Executor.Output(cmd, targetDir, action)
+}
+
+// merge returns a context that is done when either ctx or e.ctx is done.
+func (e contextExecutor) merge(ctx context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-e.ctx.Done():
+			cancel()
+		case <-merged.Done():
+		}
+	}()
+	return merged, cancel
+}
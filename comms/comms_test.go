@@ -1,11 +1,21 @@
 package comms_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
 	"github.com/vision-cli/common/execute"
 	"github.com/vision-cli/common/tmpl"
-	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	api_v1 "github.com/vision-cli/api/v1"
 	"github.com/vision-cli/common/comms"
@@ -74,6 +84,92 @@ func TestCall_WhenExecutorFails_ReturnsError(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestCallContext_WhenContextAlreadyCancelled_ReturnsTimeoutError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	e := mocks.NewMockExecutor()
+	_, err := comms.CallContext[TestMsg](ctx, plugin, &pluginRequest, &e)
+	var timeoutErr *comms.TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Empty(t, e.History())
+}
+
+func TestCallContext_WhenInternalPluginHangs_ReturnsTimeoutError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	plugin := plugins.Plugin{
+		Name: "internal-plugin",
+		InternalCommand: func(_ string, _ execute.Executor, _ tmpl.TmplWriter) string {
+			<-release
+			return `{"Msg":"hello"}`
+		},
+	}
+	e := mocks.NewMockExecutor()
+	_, err := comms.CallContext[TestMsg](ctx, plugin, &pluginRequest, &e)
+	var timeoutErr *comms.TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, "internal-plugin", timeoutErr.Plugin)
+}
+
+func TestCallContext_WhenExternalPluginHangs_KillsItAndReturnsTimeoutError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vision-plugin-hang-v1")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\nsleep 30 &\nwait\n"), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-hang-v1", PluginPath: path}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := comms.CallContext[TestMsg](ctx, plugin, &pluginRequest, execute.NewOsExecutor())
+	var timeoutErr *comms.TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestCall_WhenExternalPluginFails_ReturnsPluginError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vision-plugin-broken-v1")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho starting >&2\necho config missing >&2\nexit 3\n"), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-broken-v1", PluginPath: path}
+
+	_, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor())
+	var pluginErr *comms.PluginError
+	require.ErrorAs(t, err, &pluginErr)
+	assert.Equal(t, "vision-plugin-broken-v1", pluginErr.Plugin)
+	assert.Equal(t, path, pluginErr.Path)
+	assert.Equal(t, "run", pluginErr.Command)
+	assert.Equal(t, 3, pluginErr.ExitCode)
+	assert.Equal(t, "starting\nconfig missing\n", pluginErr.Stderr)
+	assert.Contains(t, err.Error(), "config missing")
+}
+
+func TestCall_WhenPluginWritesLotsOfStderr_KeepsOnlyTheTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vision-plugin-noisy-v1")
+	script := "#!/bin/sh\ni=0\nwhile [ $i -lt 1000 ]; do echo noise line $i >&2; i=$((i+1)); done\necho the real problem >&2\nexit 1\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-noisy-v1", PluginPath: path}
+
+	_, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor())
+	var pluginErr *comms.PluginError
+	require.ErrorAs(t, err, &pluginErr)
+	assert.LessOrEqual(t, len(pluginErr.Stderr), 4096)
+	assert.True(t, strings.HasSuffix(pluginErr.Stderr, "the real problem\n"))
+}
+
+func TestCallContext_WhenExecutorFailsWithoutTimeout_ReturnsPluginError(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutputErr(errors.New("exit status 1"))
+	_, err := comms.CallContext[TestMsg](context.Background(), plugin, &pluginRequest, &e)
+	var timeoutErr *comms.TimeoutError
+	require.False(t, errors.As(err, &timeoutErr))
+	var pluginErr *comms.PluginError
+	require.ErrorAs(t, err, &pluginErr)
+	assert.Equal(t, -1, pluginErr.ExitCode)
+	assert.Equal(t, "exit status 1", pluginErr.Err.Error())
+}
+
 var pluginRequest = api_v1.PluginRequest{
 	Command: "run",
 	Args:    []string{},
@@ -82,3 +178,62 @@ var pluginRequest = api_v1.PluginRequest{
 type TestMsg struct {
 	Msg string
 }
+
+func TestCall_WithEnforcingVerifier_RefusesModifiedPlugin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vision-plugin-verified-v1")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho '{\"Msg\":\"hello\"}'\n"), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-verified-v1", PluginPath: path}
+	sum, err := plugins.Checksum(path)
+	require.NoError(t, err)
+	v := &plugins.Verifier{Checksums: plugins.Checksums{plugin.Name: sum}, Mode: plugins.VerifyEnforce}
+
+	result, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithVerifier(v))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result.Msg)
+
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho '{\"Msg\":\"evil\"}'\n"), 0o755))
+	_, err = comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithVerifier(v))
+	var verr *plugins.VerificationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, plugins.ReasonModified, verr.Reason)
+}
+
+func TestCall_WithWarningVerifier_WarnsAndRunsModifiedPlugin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vision-plugin-verified-v1")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho '{\"Msg\":\"changed\"}'\n"), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-verified-v1", PluginPath: path}
+	v := &plugins.Verifier{Checksums: plugins.Checksums{plugin.Name: "0000"}, Mode: plugins.VerifyWarn}
+
+	var warnings []string
+	result, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithVerifier(v),
+		comms.WithWarnings(func(msg string) { warnings = append(warnings, msg) }))
+	require.NoError(t, err)
+	assert.Equal(t, "changed", result.Msg)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "vision-plugin-verified-v1")
+	assert.Contains(t, warnings[0], "has been modified")
+}
+
+func TestCallContext_WhenInternalPluginRunsCommand_StopsItOnTimeout(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "finished")
+	finished := make(chan error, 1)
+	plugin := plugins.Plugin{
+		Name: "internal-plugin",
+		InternalCommand: func(_ string, e execute.Executor, _ tmpl.TmplWriter) string {
+			cmd := exec.Command("sh", "-c", "sleep 0.5; touch "+marker)
+			finished <- e.Errors(cmd, ".", "running slow command")
+			return `{"Msg":"hello"}`
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := comms.CallContext[TestMsg](ctx, plugin, &pluginRequest, execute.OsExecutor{Mode: execute.PlainOutput, Log: io.Discard})
+	var timeoutErr *comms.TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+
+	var cancelled *execute.CancelledError
+	require.ErrorAs(t, <-finished, &cancelled)
+	time.Sleep(700 * time.Millisecond)
+	assert.NoFileExists(t, marker, "the command should have been stopped")
+}
@@ -0,0 +1,55 @@
+package comms
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxStderrTail is the number of trailing bytes of plugin stderr kept in a PluginError.
+	maxStderrTail = 4096
+)
+
+// PluginError is returned when an external plugin cannot be started or exits unsuccessfully.
+type PluginError struct {
+	Plugin   string // plugin name
+	Path     string // plugin binary path
+	Command  string // command of the request sent, e.g. api_v1.CommandRun
+	ExitCode int    // exit status of the plugin, or -1 if it did not exit normally
+	Stderr   string // the last part of the plugin's standard error output
+	Err      error  // the underlying error from the executor
+}
+
+func (e *PluginError) Error() string {
+	msg := fmt.Sprintf("cannot run plugin %s (command %q): %s", e.Plugin, e.Command, e.Err.Error())
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+func (e *PluginError) Unwrap() error {
+	return e.Err
+}
+
+// tailBuffer is an io.Writer that keeps only the last max bytes written to it.
+type tailBuffer struct {
+	max int
+	buf []byte
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.max; over > 0 {
+		b.buf = b.buf[over:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.buf)
+}
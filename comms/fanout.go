@@ -0,0 +1,66 @@
+package comms
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/plugins"
+)
+
+const (
+	defaultFanOutWorkers = 4
+)
+
+// FanOutOptions configures FanOut.
+type FanOutOptions struct {
+	Workers int           // maximum number of plugins called at once, 4 if zero
+	Timeout time.Duration // limit on each plugin call, none if zero
+}
+
+// Result is the outcome of calling one plugin in FanOut.
+type Result[T any] struct {
+	Plugin plugins.Plugin
+	Value  *T
+	Err    error
+}
+
+// FanOut sends request to every plugin concurrently and returns one result per plugin,
+// in the same order as plugins. A failing plugin does not stop the others.
+func FanOut[T any](ctx context.Context, all []plugins.Plugin, request *api_v1.PluginRequest, executor execute.Executor, options FanOutOptions, opts ...CallOption) []Result[T] {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = defaultFanOutWorkers
+	}
+
+	results := make([]Result[T], len(all))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(all); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = callOne[T](ctx, all[i], request, executor, options.Timeout, opts)
+			}
+		}()
+	}
+	for i := range all {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+func callOne[T any](ctx context.Context, plugin plugins.Plugin, request *api_v1.PluginRequest, executor execute.Executor, timeout time.Duration, opts []CallOption) Result[T] {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	value, err := CallContext[T](ctx, plugin, request, executor, opts...)
+	return Result[T]{Plugin: plugin, Value: value, Err: err}
+}
@@ -0,0 +1,95 @@
+package comms_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vision-cli/common/comms"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/mocks"
+	"github.com/vision-cli/common/plugins"
+	"github.com/vision-cli/common/tmpl"
+)
+
+func internalPlugin(name string, command func() string) plugins.Plugin {
+	return plugins.Plugin{
+		Name: name,
+		InternalCommand: func(_ string, _ execute.Executor, _ tmpl.TmplWriter) string {
+			return command()
+		},
+	}
+}
+
+func TestFanOut_ReturnsResultsInPluginOrder(t *testing.T) {
+	var all []plugins.Plugin
+	for i := 0; i < 10; i++ {
+		i := i
+		all = append(all, internalPlugin(fmt.Sprintf("plugin-%d", i), func() string {
+			time.Sleep(time.Duration(10-i) * time.Millisecond)
+			return fmt.Sprintf(`{"Msg":"%d"}`, i)
+		}))
+	}
+	e := mocks.NewMockExecutor()
+	results := comms.FanOut[TestMsg](context.Background(), all, &pluginRequest, &e, comms.FanOutOptions{Workers: 3})
+	require.Len(t, results, 10)
+	for i, r := range results {
+		require.NoError(t, r.Err)
+		assert.Equal(t, fmt.Sprintf("plugin-%d", i), r.Plugin.Name)
+		assert.Equal(t, fmt.Sprint(i), r.Value.Msg)
+	}
+}
+
+func TestFanOut_NeverExceedsWorkerLimit(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	command := func() string {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return `{"Msg":"ok"}`
+	}
+	var all []plugins.Plugin
+	for i := 0; i < 12; i++ {
+		all = append(all, internalPlugin(fmt.Sprintf("plugin-%d", i), command))
+	}
+	e := mocks.NewMockExecutor()
+	comms.FanOut[TestMsg](context.Background(), all, &pluginRequest, &e, comms.FanOutOptions{Workers: 2})
+	assert.LessOrEqual(t, peak, 2)
+}
+
+func TestFanOut_WhenOnePluginIsSlowOrFails_OthersStillSucceed(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	all := []plugins.Plugin{
+		internalPlugin("fast", func() string { return `{"Msg":"fast"}` }),
+		internalPlugin("slow", func() string { <-release; return `{"Msg":"slow"}` }),
+		internalPlugin("failing", func() string { return `{"Result":"","Error":"broken"}` }),
+	}
+	e := mocks.NewMockExecutor()
+	results := comms.FanOut[TestMsg](context.Background(), all, &pluginRequest, &e, comms.FanOutOptions{Timeout: 20 * time.Millisecond})
+
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, "fast", results[0].Value.Msg)
+	var timeoutErr *comms.TimeoutError
+	require.ErrorAs(t, results[1].Err, &timeoutErr)
+	assert.Nil(t, results[1].Value)
+	require.EqualError(t, results[2].Err, "broken")
+}
+
+func TestFanOut_WhenNoPlugins_ReturnsNoResults(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	results := comms.FanOut[TestMsg](context.Background(), nil, &pluginRequest, &e, comms.FanOutOptions{})
+	assert.Empty(t, results)
+}
@@ -0,0 +1,192 @@
+package comms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/marshal"
+	"github.com/vision-cli/common/plugins"
+)
+
+const (
+	// HandshakeEnvVar is set to "1" in the environment of a plugin run by Handshake.
+	HandshakeEnvVar = "VISION_PLUGIN_HANDSHAKE"
+
+	// APIVersionEnvVar holds the negotiated API version when a plugin is called with WithProtocol.
+	APIVersionEnvVar = "VISION_PLUGIN_API_VERSION"
+
+	// EncodingEnvVar holds the name of the negotiated marshal codec when a plugin is called with
+	// WithProtocol. Requests are sent and responses expected in that encoding. Plugins that are
+	// run without it use JSON.
+	EncodingEnvVar = "VISION_PLUGIN_ENCODING"
+
+	// legacyVersion is the API version assumed for plugins that do not understand the handshake.
+	legacyVersion = "v1"
+)
+
+// Protocol features a plugin can advertise during the handshake.
+const (
+	CapabilityStream = "stream"
+	CapabilityServe  = "serve"
+)
+
+// hostCapabilities are the protocol features this host supports.
+var hostCapabilities = []string{CapabilityStream, CapabilityServe}
+
+// defaultEncodings are the marshal codecs the host offers unless WithEncodings is used, most
+// preferred first. Binary encodings such as CBOR are opt-in.
+var defaultEncodings = []string{marshal.JSON}
+
+// RequestFormat encodes requests for one API version.
+type RequestFormat struct {
+	Version string
+	Encode  func(codec marshal.Codec, request *api_v1.PluginRequest) (string, error)
+}
+
+// defaultRequestFormats are the formats the host sends unless WithRequestFormats is used.
+var defaultRequestFormats = []RequestFormat{
+	{Version: "v1", Encode: marshal.MarshalWith[*api_v1.PluginRequest]},
+}
+
+// DefaultRequestFormats returns the formats the host can send, most preferred first. To keep
+// talking to older plugins, append a format that converts to an older request shape and pass the
+// result to WithRequestFormats.
+func DefaultRequestFormats() []RequestFormat {
+	return append([]RequestFormat{}, defaultRequestFormats...)
+}
+
+// Hello is written to the stdin of a plugin run with HandshakeEnvVar set.
+type Hello struct {
+	Versions     []string // API versions the host supports, most preferred first
+	Capabilities []string // protocol features the host supports
+	Encodings    []string // marshal codecs the host supports, most preferred first
+}
+
+// HelloReply is the plugin's answer to Hello, written to its stdout.
+type HelloReply struct {
+	Versions     []string // API versions the plugin supports
+	Capabilities []string // protocol features the plugin supports
+	Encodings    []string // marshal codecs the plugin supports, JSON only if empty
+}
+
+// Protocol is the outcome of a handshake.
+type Protocol struct {
+	Version      string   // the API version requests are sent in
+	Capabilities []string // protocol features supported by both host and plugin
+	Legacy       bool     // true if the plugin did not understand the handshake
+	Encoding     string   // the marshal codec requests and responses are sent in, JSON if empty
+	format       RequestFormat
+	codec        marshal.Codec
+}
+
+// Supports returns true if both host and plugin support capability.
+func (p *Protocol) Supports(capability string) bool {
+	return contains(p.Capabilities, capability)
+}
+
+// IncompatibleError is returned by Handshake when host and plugin share no API version.
+type IncompatibleError struct {
+	Plugin         string
+	HostVersions   []string
+	PluginVersions []string
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("plugin %s is incompatible: it supports API versions [%s] but this host supports [%s]",
+		e.Plugin, strings.Join(e.PluginVersions, ", "), strings.Join(e.HostVersions, ", "))
+}
+
+// Handshake asks plugin which API versions, capabilities and encodings it supports and picks the
+// first request format the plugin understands, from DefaultRequestFormats or those set with
+// WithRequestFormats, and the first encoding it understands, JSON unless others are set with
+// WithEncodings. Plugins that predate the handshake are assumed to speak v1 in JSON with no extra
+// capabilities.
+func Handshake(ctx context.Context, plugin plugins.Plugin, executor execute.Executor, opts ...CallOption) (*Protocol, error) {
+	if executor == nil {
+		return nil, fmt.Errorf("comms.Handshake called with nil executor")
+	}
+	o := newCallOptions(opts)
+	formats := o.formats
+	if len(formats) == 0 {
+		formats = defaultRequestFormats
+	}
+	hostVersions := make([]string, len(formats))
+	for i, f := range formats {
+		hostVersions[i] = f.Version
+	}
+	if plugin.InternalCommand != nil {
+		// internal plugins are built with the host
+		return &Protocol{Version: formats[0].Version, format: formats[0]}, nil
+	}
+
+	encodings := o.encodings
+	if len(encodings) == 0 {
+		encodings = defaultEncodings
+	}
+	hello, err := marshal.Marshal(Hello{Versions: hostVersions, Capabilities: hostCapabilities, Encodings: encodings})
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal handshake for plugin %s: %s", plugin.Name, err.Error())
+	}
+	reply := HelloReply{Versions: []string{legacyVersion}}
+	legacy := false
+	// the handshake runs under the same policy and checks as calls, but before any protocol is agreed
+	handshake := *o
+	handshake.protocol = nil
+	handshake.cache = nil
+	response, err := send(ctx, plugin, "handshake", hello, executor, &handshake, HandshakeEnvVar+"=1")
+	if err != nil {
+		var pluginErr *PluginError
+		if !errors.As(err, &pluginErr) || pluginErr.ExitCode < 0 {
+			return nil, err
+		}
+		legacy = true
+	} else if decoded, err := marshal.Unmarshal[HelloReply](response); err == nil && len(decoded.Versions) > 0 {
+		reply = decoded
+	} else {
+		legacy = true
+	}
+
+	for _, f := range formats {
+		if contains(reply.Versions, f.Version) {
+			p := &Protocol{
+				Version:      f.Version,
+				Capabilities: intersect(hostCapabilities, reply.Capabilities),
+				Legacy:       legacy,
+				format:       f,
+			}
+			if shared := intersect(encodings, reply.Encodings); len(shared) > 0 {
+				codec, err := marshal.CodecFor(shared[0])
+				if err != nil {
+					return nil, fmt.Errorf("cannot encode requests for plugin %s: %w", plugin.Name, err)
+				}
+				p.Encoding = shared[0]
+				p.codec = codec
+			}
+			return p, nil
+		}
+	}
+	return nil, &IncompatibleError{Plugin: plugin.Name, HostVersions: hostVersions, PluginVersions: reply.Versions}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func intersect(a []string, b []string) []string {
+	var both []string
+	for _, v := range a {
+		if contains(b, v) {
+			both = append(both, v)
+		}
+	}
+	return both
+}
@@ -0,0 +1,126 @@
+package comms_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/comms"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/marshal"
+	"github.com/vision-cli/common/mocks"
+	"github.com/vision-cli/common/plugins"
+	"github.com/vision-cli/common/tmpl"
+)
+
+func TestHandshake_WhenPluginSupportsHandshake_NegotiatesVersionAndCapabilities(t *testing.T) {
+	plugin := plugins.Plugin{Name: "vision-plugin-helper-v1", PluginPath: helperPlugin(t)}
+	p, err := comms.Handshake(context.Background(), plugin, execute.NewOsExecutor())
+	require.NoError(t, err)
+	assert.Equal(t, "v1", p.Version)
+	assert.False(t, p.Legacy)
+	assert.True(t, p.Supports(comms.CapabilityServe))
+	assert.True(t, p.Supports(comms.CapabilityStream))
+	assert.Empty(t, p.Encoding, "the helper lists no encodings, so requests stay JSON")
+}
+
+func TestHandshake_WhenPluginListsEncodings_PicksFirstHostEncoding(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput(`{"Versions":["v1"],"Encodings":["yaml","cbor","json"]}`)
+
+	p, err := comms.Handshake(context.Background(), plugin, &e)
+	require.NoError(t, err)
+	assert.Equal(t, marshal.JSON, p.Encoding)
+
+	p, err = comms.Handshake(context.Background(), plugin, &e, comms.WithEncodings(marshal.CBOR, marshal.JSON))
+	require.NoError(t, err)
+	assert.Equal(t, marshal.CBOR, p.Encoding)
+
+	p, err = comms.Handshake(context.Background(), plugin, &e, comms.WithEncodings(marshal.YAML, marshal.JSON))
+	require.NoError(t, err)
+	assert.Equal(t, marshal.YAML, p.Encoding)
+}
+
+func TestHandshake_WhenNoEncodingInCommon_UsesJSON(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput(`{"Versions":["v1"],"Encodings":["msgpack"]}`)
+
+	p, err := comms.Handshake(context.Background(), plugin, &e)
+	require.NoError(t, err)
+	assert.Empty(t, p.Encoding)
+}
+
+func TestHandshake_WhenPluginPredatesHandshake_AssumesLegacyV1(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput(`{"Result":"","Error":"json: unknown field \"Versions\""}`)
+	p, err := comms.Handshake(context.Background(), plugin, &e)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", p.Version)
+	assert.True(t, p.Legacy)
+	assert.Empty(t, p.Capabilities)
+}
+
+func TestHandshake_WhenLegacyPluginExitsWithError_AssumesLegacyV1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vision-plugin-legacy-v1")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho 'bad request' >&2\nexit 1\n"), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-legacy-v1", PluginPath: path}
+
+	p, err := comms.Handshake(context.Background(), plugin, execute.NewOsExecutor())
+	require.NoError(t, err)
+	assert.True(t, p.Legacy)
+}
+
+func TestHandshake_WhenPluginCannotStart_ReturnsError(t *testing.T) {
+	plugin := plugins.Plugin{Name: "vision-plugin-missing-v1", PluginPath: filepath.Join(t.TempDir(), "missing")}
+	_, err := comms.Handshake(context.Background(), plugin, execute.NewOsExecutor())
+	var pluginErr *comms.PluginError
+	require.ErrorAs(t, err, &pluginErr)
+}
+
+func TestHandshake_WhenNoVersionInCommon_ReturnsIncompatibleError(t *testing.T) {
+	plugin := plugins.Plugin{Name: "vision-plugin-helper-v1", PluginPath: helperPlugin(t)}
+	v2 := comms.RequestFormat{Version: "v2", Encode: func(marshal.Codec, *api_v1.PluginRequest) (string, error) { return "", nil }}
+	_, err := comms.Handshake(context.Background(), plugin, execute.NewOsExecutor(), comms.WithRequestFormats(v2))
+	var incompatible *comms.IncompatibleError
+	require.ErrorAs(t, err, &incompatible)
+	assert.Equal(t, []string{"v2"}, incompatible.HostVersions)
+	assert.Equal(t, []string{"v1"}, incompatible.PluginVersions)
+	assert.Equal(t, "plugin vision-plugin-helper-v1 is incompatible: it supports API versions [v1] but this host supports [v2]", err.Error())
+}
+
+func TestHandshake_WhenPluginOnlySupportsOlderVersion_DowngradesRequestFormat(t *testing.T) {
+	plugin := plugins.Plugin{Name: "vision-plugin-helper-v1", PluginPath: helperPlugin(t)}
+	downgraded := false
+	v1 := comms.RequestFormat{Version: "v1", Encode: func(c marshal.Codec, r *api_v1.PluginRequest) (string, error) {
+		downgraded = true
+		return comms.DefaultRequestFormats()[0].Encode(c, r)
+	}}
+	v2 := comms.RequestFormat{Version: "v2", Encode: func(marshal.Codec, *api_v1.PluginRequest) (string, error) { return "", nil }}
+
+	p, err := comms.Handshake(context.Background(), plugin, execute.NewOsExecutor(), comms.WithRequestFormats(v2, v1))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", p.Version)
+
+	result, err := comms.Call[TestMsg](plugin, &api_v1.PluginRequest{Command: "run", Args: []string{"x"}}, execute.NewOsExecutor(), comms.WithProtocol(p))
+	require.NoError(t, err)
+	assert.Equal(t, "one-shot x", result.Msg)
+	assert.True(t, downgraded)
+}
+
+func TestHandshake_WhenPluginIsInternal_UsesHostVersion(t *testing.T) {
+	plugin := plugins.Plugin{
+		Name: "internal-plugin",
+		InternalCommand: func(_ string, _ execute.Executor, _ tmpl.TmplWriter) string {
+			t.Fatal("internal plugin should not be called")
+			return ""
+		},
+	}
+	e := mocks.NewMockExecutor()
+	p, err := comms.Handshake(context.Background(), plugin, &e)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", p.Version)
+}
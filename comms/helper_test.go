@@ -0,0 +1,94 @@
+package comms_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/comms"
+)
+
+// helperEnvVar makes the test binary act as a plugin, so tests can run real plugin processes.
+const helperEnvVar = "COMMS_TEST_HELPER_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnvVar) == "" {
+		os.Exit(m.Run())
+	}
+	switch {
+	case os.Getenv(comms.HandshakeEnvVar) == "1":
+		helperHandshake()
+	case os.Getenv(comms.ServeEnvVar) == "1":
+		helperServe()
+	default:
+		helperOneShot()
+	}
+	os.Exit(0)
+}
+
+// helperPlugin returns the path of a plugin that runs this test binary in helper mode.
+func helperPlugin(t *testing.T) string {
+	t.Helper()
+	t.Setenv(helperEnvVar, "1")
+	return os.Args[0]
+}
+
+// helperHandshake supports v1 and every capability.
+func helperHandshake() {
+	_, _ = io.ReadAll(os.Stdin)
+	reply, _ := json.Marshal(comms.HelloReply{
+		Versions:     []string{"v1"},
+		Capabilities: []string{comms.CapabilityStream, comms.CapabilityServe},
+	})
+	fmt.Println(string(reply))
+}
+
+// helperOneShot answers a single request with its arguments joined.
+func helperOneShot() {
+	data, _ := io.ReadAll(os.Stdin)
+	var request api_v1.PluginRequest
+	_ = json.Unmarshal(data, &request)
+	fmt.Printf(`{"Msg":"one-shot %s"}`, strings.Join(request.Args, " "))
+}
+
+// helperServe answers requests concurrently. A first argument that parses as a duration delays the answer.
+func helperServe() {
+	fmt.Println(`{"Serve":true}`)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var frame comms.ServeRequest
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			os.Exit(2)
+		}
+		var request api_v1.PluginRequest
+		if err := json.Unmarshal(frame.Request, &request); err != nil {
+			os.Exit(2)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if len(request.Args) > 0 {
+				if delay, err := time.ParseDuration(request.Args[0]); err == nil {
+					time.Sleep(delay)
+				}
+			}
+			response, _ := json.Marshal(comms.ServeResponse{
+				ID:       frame.ID,
+				Response: json.RawMessage(fmt.Sprintf(`{"Msg":"pid %d %s"}`, os.Getpid(), strings.Join(request.Args, " "))),
+			})
+			mu.Lock()
+			fmt.Println(string(response))
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+}
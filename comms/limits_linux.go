@@ -0,0 +1,40 @@
+package comms
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	bytesPerKiB = 1024
+)
+
+// applyLimits runs cmd through sh, which sets the limits with ulimit before replacing
+// itself with the plugin, so the limits are in place before the plugin starts.
+func applyLimits(cmd *exec.Cmd, limits Limits) error {
+	if limits.isZero() {
+		return nil
+	}
+	shell, err := exec.LookPath("sh")
+	if err != nil {
+		return fmt.Errorf("applying plugin resource limits: %w", err)
+	}
+	var script []string
+	if limits.CPUTime > 0 {
+		seconds := (limits.CPUTime + time.Second - 1) / time.Second
+		script = append(script, fmt.Sprintf("ulimit -t %d", seconds))
+	}
+	if limits.Memory > 0 {
+		script = append(script, fmt.Sprintf("ulimit -v %d", (limits.Memory+bytesPerKiB-1)/bytesPerKiB))
+	}
+	if limits.OpenFiles > 0 {
+		script = append(script, fmt.Sprintf("ulimit -n %d", limits.OpenFiles))
+	}
+	script = append(script, `exec "$0" "$@"`)
+
+	cmd.Args = append([]string{"sh", "-c", strings.Join(script, " && "), cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = shell
+	return nil
+}
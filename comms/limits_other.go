@@ -0,0 +1,17 @@
+//go:build !linux
+
+package comms
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// applyLimits fails for any limits, as they are only supported on Linux.
+func applyLimits(cmd *exec.Cmd, limits Limits) error {
+	if limits.isZero() {
+		return nil
+	}
+	return fmt.Errorf("plugin resource limits are not supported on %s", runtime.GOOS)
+}
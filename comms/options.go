@@ -0,0 +1,178 @@
+package comms
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/marshal"
+	"github.com/vision-cli/common/plugins"
+)
+
+// jsonCodec is used when no other encoding has been negotiated.
+var jsonCodec, _ = marshal.CodecFor(marshal.JSON)
+
+// CallOption configures how a plugin is called.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	protocol  *Protocol
+	verifier  *plugins.Verifier
+	formats   []RequestFormat
+	policy    *Policy
+	cache     *Cache
+	encodings []string
+	warn      func(msg string)
+	start     time.Duration
+}
+
+// WithProtocol sends requests in the format negotiated by Handshake.
+func WithProtocol(p *Protocol) CallOption {
+	return func(o *callOptions) {
+		o.protocol = p
+	}
+}
+
+// WithRequestFormats offers formats, most preferred first, instead of DefaultRequestFormats during
+// Handshake.
+func WithRequestFormats(formats ...RequestFormat) CallOption {
+	return func(o *callOptions) {
+		o.formats = formats
+	}
+}
+
+// WithEncodings offers the marshal codecs called names, most preferred first, instead of JSON
+// during Handshake.
+func WithEncodings(names ...string) CallOption {
+	return func(o *callOptions) {
+		o.encodings = names
+	}
+}
+
+// WithVerifier checks the plugin binary with v immediately before each run. When v enforces
+// verification, a plugin that fails is not run and its *plugins.VerificationError is returned.
+// Otherwise the failure is reported as a warning and the plugin is run.
+func WithVerifier(v *plugins.Verifier) CallOption {
+	return func(o *callOptions) {
+		o.verifier = v
+	}
+}
+
+// WithStartTimeout sets how long StartSession waits for a plugin that advertised serve mode to
+// announce itself before falling back to one-shot calls, instead of DefaultStartTimeout.
+func WithStartTimeout(d time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.start = d
+	}
+}
+
+// WithWarnings reports warnings, such as plugins failing verification, to warn instead of stderr.
+func WithWarnings(warn func(msg string)) CallOption {
+	return func(o *callOptions) {
+		o.warn = warn
+	}
+}
+
+// WithPolicy runs external plugins with the environment, working directory and resource limits of p.
+func WithPolicy(p Policy) CallOption {
+	return func(o *callOptions) {
+		o.policy = &p
+	}
+}
+
+// WithCache answers calls from c when it holds a fresh response to the same request from the same
+// plugin binary, and stores new responses in c. Use it only for requests whose response depends on
+// nothing but the request, such as usage.
+func WithCache(c *Cache) CallOption {
+	return func(o *callOptions) {
+		o.cache = c
+	}
+}
+
+func newCallOptions(opts []CallOption) *callOptions {
+	o := &callOptions{
+		warn: func(msg string) {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// encode marshals request in the negotiated format and encoding, or the current format in JSON if
+// there was no handshake.
+func (o *callOptions) encode(request *api_v1.PluginRequest) (string, error) {
+	if o.protocol != nil {
+		return o.protocol.format.Encode(o.codec(), request)
+	}
+	return marshal.Marshal(request)
+}
+
+// codec returns the codec of the negotiated encoding, or strict JSON if none was negotiated.
+func (o *callOptions) codec() marshal.Codec {
+	if o.protocol != nil && o.protocol.codec != nil {
+		return o.protocol.codec
+	}
+	return jsonCodec
+}
+
+// framed returns o, or a copy of o that sends JSON if another encoding was negotiated.
+// Streams and sessions wrap requests and responses in JSON lines, so they always use JSON.
+func (o *callOptions) framed() *callOptions {
+	if o.protocol == nil || o.protocol.codec == nil || o.protocol.Encoding == marshal.JSON {
+		return o
+	}
+	protocol := *o.protocol
+	protocol.Encoding = marshal.JSON
+	protocol.codec = jsonCodec
+	framed := *o
+	framed.protocol = &protocol
+	return &framed
+}
+
+// verify returns an error if an external plugin must not be run, and warns about plugins that
+// fail verification but may still run.
+func (o *callOptions) verify(plugin plugins.Plugin) error {
+	if o.verifier == nil {
+		return nil
+	}
+	err := o.verifier.Verify(plugin)
+	if err == nil || o.verifier.Mode == plugins.VerifyEnforce {
+		return err
+	}
+	o.warn(err.Error())
+	return nil
+}
+
+// dir returns the working directory for external plugins.
+func (o *callOptions) dir() string {
+	if o.policy == nil {
+		return "."
+	}
+	return o.policy.dir()
+}
+
+// prepare sets up the environment, working directory and limits of cmd, which runs an external
+// plugin. env is added to the environment after the protocol variables.
+func (o *callOptions) prepare(cmd *exec.Cmd, env ...string) error {
+	if o.protocol != nil {
+		protocolEnv := []string{fmt.Sprintf("%s=%s", APIVersionEnvVar, o.protocol.Version)}
+		if o.protocol.Encoding != "" {
+			protocolEnv = append(protocolEnv, fmt.Sprintf("%s=%s", EncodingEnvVar, o.protocol.Encoding))
+		}
+		env = append(protocolEnv, env...)
+	}
+	cmd.Dir = o.dir()
+	if o.policy == nil {
+		if len(env) > 0 {
+			cmd.Env = append(os.Environ(), env...)
+		}
+		return nil
+	}
+	cmd.Env = append(o.policy.environ(os.Environ()), env...)
+	return applyLimits(cmd, o.policy.Limits)
+}
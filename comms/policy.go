@@ -0,0 +1,55 @@
+package comms
+
+import (
+	"strings"
+	"time"
+)
+
+// Policy restricts how external plugins are run. It has no effect on internal plugins,
+// which run inside the host process.
+type Policy struct {
+	Env    []string // names of the host environment variables passed to the plugin
+	Dir    string   // working directory of the plugin, the current directory if empty
+	Limits Limits
+}
+
+// Limits are resource limits for a plugin process and its children. Zero means no limit.
+// Limits are only supported on Linux; elsewhere calls with limits fail.
+type Limits struct {
+	CPUTime   time.Duration // CPU time, rounded up to whole seconds
+	Memory    uint64        // virtual memory in bytes, rounded up to whole KiB
+	OpenFiles uint64        // number of open file descriptors
+}
+
+// DefaultPolicy passes only the environment plugins need to find their tools and run go commands.
+func DefaultPolicy() Policy {
+	return Policy{
+		Env: []string{
+			"PATH", "HOME", "USER", "TMPDIR", "LANG", "TERM",
+			"GOPATH", "GOBIN", "GOROOT", "GOCACHE", "GOMODCACHE", "GOFLAGS", "GOPROXY", "GOPRIVATE",
+		},
+	}
+}
+
+// environ returns the entries of environ whose names the policy allows.
+func (p Policy) environ(environ []string) []string {
+	allowed := []string{}
+	for _, entry := range environ {
+		name, _, _ := strings.Cut(entry, "=")
+		if contains(p.Env, name) {
+			allowed = append(allowed, entry)
+		}
+	}
+	return allowed
+}
+
+func (p Policy) dir() string {
+	if p.Dir == "" {
+		return "."
+	}
+	return p.Dir
+}
+
+func (l Limits) isZero() bool {
+	return l == Limits{}
+}
@@ -0,0 +1,100 @@
+package comms_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vision-cli/common/comms"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/plugins"
+)
+
+// reportingPlugin writes a plugin that responds with the output of script as its message.
+func reportingPlugin(t *testing.T, script string) plugins.Plugin {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vision-plugin-report-v1")
+	content := "#!/bin/sh\nmsg=$({ " + script + "; } | tr '\\n' ' ')\nprintf '{\"Msg\":\"%s\"}' \"$msg\"\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o755))
+	return plugins.Plugin{Name: "vision-plugin-report-v1", PluginPath: path}
+}
+
+func TestCall_WithPolicy_PassesOnlyAllowedEnvironment(t *testing.T) {
+	t.Setenv("VISION_TEST_SECRET", "secret")
+	t.Setenv("VISION_TEST_ALLOWED", "allowed")
+	plugin := reportingPlugin(t, "env | grep VISION_TEST")
+
+	policy := comms.Policy{Env: []string{"PATH", "VISION_TEST_ALLOWED"}}
+	result, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithPolicy(policy))
+	require.NoError(t, err)
+	assert.Contains(t, result.Msg, "VISION_TEST_ALLOWED=allowed")
+	assert.NotContains(t, result.Msg, "secret")
+}
+
+func TestCall_WithPolicyDir_RunsPluginInDir(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	plugin := reportingPlugin(t, "pwd")
+
+	result, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithPolicy(comms.Policy{Env: []string{"PATH"}, Dir: dir}))
+	require.NoError(t, err)
+	assert.Equal(t, dir, strings.TrimSpace(result.Msg))
+}
+
+func TestCall_WithPolicyLimits_AppliesLimitsToPlugin(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only supported on linux")
+	}
+	plugin := reportingPlugin(t, "ulimit -t; ulimit -v; ulimit -n")
+
+	policy := comms.DefaultPolicy()
+	policy.Limits = comms.Limits{CPUTime: 1500 * time.Millisecond, Memory: 512 << 20, OpenFiles: 64}
+	result, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor(), comms.WithPolicy(policy))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2", "524288", "64"}, strings.Fields(result.Msg))
+}
+
+func TestCall_WithoutPolicy_InheritsEnvironment(t *testing.T) {
+	t.Setenv("VISION_TEST_INHERITED", "inherited")
+	plugin := reportingPlugin(t, "env | grep VISION_TEST")
+
+	result, err := comms.Call[TestMsg](plugin, &pluginRequest, execute.NewOsExecutor())
+	require.NoError(t, err)
+	assert.Contains(t, result.Msg, "VISION_TEST_INHERITED=inherited")
+}
+
+func TestStartSession_WithPolicy_RestrictsHandshakeEnvironmentAndDir(t *testing.T) {
+	t.Setenv("SECRET_TOKEN", "secret")
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	report := filepath.Join(t.TempDir(), "handshake-env")
+	path := filepath.Join(t.TempDir(), "vision-plugin-policy-v1")
+	script := "#!/bin/sh\n" +
+		"cat > /dev/null\n" +
+		"if [ \"$" + comms.HandshakeEnvVar + "\" = 1 ]; then\n" +
+		"  { env; echo \"PWD=$(pwd)\"; } > " + report + "\n" +
+		"  echo '{\"Versions\":[\"v1\"]}'\n" +
+		"  exit 0\n" +
+		"fi\n" +
+		"echo '{\"Msg\":\"hello\"}'\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-policy-v1", PluginPath: path}
+
+	policy := comms.DefaultPolicy()
+	policy.Dir = dir
+	s, err := comms.StartSession(context.Background(), plugin, execute.NewOsExecutor(), comms.WithPolicy(policy))
+	require.NoError(t, err)
+	defer s.Close()
+
+	env, err := os.ReadFile(report)
+	require.NoError(t, err)
+	assert.NotContains(t, string(env), "SECRET_TOKEN")
+	assert.Contains(t, string(env), comms.HandshakeEnvVar+"=1")
+	assert.Contains(t, string(env), "PWD="+dir+"\n")
+}
@@ -0,0 +1,140 @@
+package comms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/plugins"
+)
+
+// RequestError lists the problems found while building a request.
+type RequestError struct {
+	Problems []string
+}
+
+func (e *RequestError) Error() string {
+	return "invalid plugin request: " + strings.Join(e.Problems, "; ")
+}
+
+// RequestBuilder builds an api_v1.PluginRequest, checking it before it is sent to a plugin.
+type RequestBuilder struct {
+	request api_v1.PluginRequest
+}
+
+// NewRequest starts a request for command, one of the api_v1 Command constants.
+func NewRequest(command string) *RequestBuilder {
+	return &RequestBuilder{request: api_v1.PluginRequest{Command: command, Args: []string{}}}
+}
+
+// Args appends sub commands such as create or delete to a run request.
+func (b *RequestBuilder) Args(args ...string) *RequestBuilder {
+	b.request.Args = append(b.request.Args, args...)
+	return b
+}
+
+// Flag adds a flag from the command line to a run request.
+func (b *RequestBuilder) Flag(flag api_v1.PluginFlag) *RequestBuilder {
+	b.request.Flags = append(b.request.Flags, flag)
+	return b
+}
+
+// Placeholders sets the placeholders of a run request.
+func (b *RequestBuilder) Placeholders(p api_v1.PluginPlaceholders) *RequestBuilder {
+	b.request.Placeholders = p
+	return b
+}
+
+// Build returns the request, or a *RequestError listing everything wrong with it.
+func (b *RequestBuilder) Build() (*api_v1.PluginRequest, error) {
+	var problems []string
+	r := b.request
+	switch r.Command {
+	case api_v1.CommandUsage, api_v1.CommandConfig:
+		if len(r.Args) > 0 || len(r.Flags) > 0 || r.Placeholders != (api_v1.PluginPlaceholders{}) {
+			problems = append(problems, fmt.Sprintf("%s request must not have args, flags or placeholders", r.Command))
+		}
+	case api_v1.CommandRun:
+		problems = append(problems, checkArgs(r.Args)...)
+		problems = append(problems, checkFlags(r.Flags)...)
+	default:
+		problems = append(problems, fmt.Sprintf("unknown command %q, expected %s, %s or %s",
+			r.Command, api_v1.CommandUsage, api_v1.CommandConfig, api_v1.CommandRun))
+	}
+	if len(problems) > 0 {
+		return nil, &RequestError{Problems: problems}
+	}
+	return &r, nil
+}
+
+func checkArgs(args []string) []string {
+	var problems []string
+	for i, arg := range args {
+		if strings.TrimSpace(arg) == "" {
+			problems = append(problems, fmt.Sprintf("arg %d is empty", i))
+		}
+	}
+	return problems
+}
+
+func checkFlags(flags []api_v1.PluginFlag) []string {
+	var problems []string
+	names := map[string]bool{}
+	shorthands := map[string]bool{}
+	for i, flag := range flags {
+		switch {
+		case flag.Name == "":
+			problems = append(problems, fmt.Sprintf("flag %d has no name", i))
+		case names[flag.Name]:
+			problems = append(problems, fmt.Sprintf("flag %q is repeated", flag.Name))
+		}
+		names[flag.Name] = true
+		if flag.Shorthand == "" {
+			continue
+		}
+		switch {
+		case utf8.RuneCountInString(flag.Shorthand) != 1:
+			problems = append(problems, fmt.Sprintf("flag %q shorthand %q must be a single character", flag.Name, flag.Shorthand))
+		case shorthands[flag.Shorthand]:
+			problems = append(problems, fmt.Sprintf("flag %q shorthand %q is already used", flag.Name, flag.Shorthand))
+		}
+		shorthands[flag.Shorthand] = true
+	}
+	return problems
+}
+
+// Send builds the request from b and calls plugin with it, decoding the response into T.
+func Send[T any](ctx context.Context, plugin plugins.Plugin, b *RequestBuilder, executor execute.Executor, opts ...CallOption) (*T, error) {
+	request, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return CallContext[T](ctx, plugin, request, executor, opts...)
+}
+
+// Usage asks plugin for its usage.
+func Usage(ctx context.Context, plugin plugins.Plugin, executor execute.Executor, opts ...CallOption) (*api_v1.PluginUsageResponse, error) {
+	return Send[api_v1.PluginUsageResponse](ctx, plugin, NewRequest(api_v1.CommandUsage), executor, opts...)
+}
+
+// Config asks plugin for its default config.
+func Config(ctx context.Context, plugin plugins.Plugin, executor execute.Executor, opts ...CallOption) (*api_v1.PluginConfigResponse, error) {
+	return Send[api_v1.PluginConfigResponse](ctx, plugin, NewRequest(api_v1.CommandConfig), executor, opts...)
+}
+
+// Run sends a run request built with b to plugin and returns its result.
+// A response with an error is returned as an error.
+func Run(ctx context.Context, plugin plugins.Plugin, b *RequestBuilder, executor execute.Executor, opts ...CallOption) (string, error) {
+	response, err := Send[api_v1.PluginResponse](ctx, plugin, b, executor, opts...)
+	if err != nil {
+		return "", err
+	}
+	if response.Error != "" {
+		return "", errors.New(response.Error)
+	}
+	return response.Result, nil
+}
@@ -0,0 +1,103 @@
+package comms_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/comms"
+	"github.com/vision-cli/common/mocks"
+)
+
+func TestBuild_WithValidRunRequest_ReturnsRequest(t *testing.T) {
+	request, err := comms.NewRequest(api_v1.CommandRun).
+		Args("create", "service").
+		Flag(api_v1.PluginFlag{Name: "name", Shorthand: "n", Value: "orders"}).
+		Flag(api_v1.PluginFlag{Name: "force"}).
+		Placeholders(api_v1.PluginPlaceholders{ProjectName: "shop"}).
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, &api_v1.PluginRequest{
+		Command:      api_v1.CommandRun,
+		Args:         []string{"create", "service"},
+		Flags:        []api_v1.PluginFlag{{Name: "name", Shorthand: "n", Value: "orders"}, {Name: "force"}},
+		Placeholders: api_v1.PluginPlaceholders{ProjectName: "shop"},
+	}, request)
+}
+
+func TestBuild_WithUsageRequest_ReturnsRequest(t *testing.T) {
+	request, err := comms.NewRequest(api_v1.CommandUsage).Build()
+	require.NoError(t, err)
+	assert.Equal(t, api_v1.CommandUsage, request.Command)
+}
+
+func TestBuild_WithUnknownCommand_ReturnsRequestError(t *testing.T) {
+	_, err := comms.NewRequest("deploy").Build()
+	var requestErr *comms.RequestError
+	require.ErrorAs(t, err, &requestErr)
+	assert.Equal(t, `invalid plugin request: unknown command "deploy", expected usage, config or run`, err.Error())
+}
+
+func TestBuild_WithArgsOnConfigRequest_ReturnsRequestError(t *testing.T) {
+	_, err := comms.NewRequest(api_v1.CommandConfig).Args("create").Build()
+	var requestErr *comms.RequestError
+	require.ErrorAs(t, err, &requestErr)
+}
+
+func TestBuild_WithBadFlags_ReportsEveryProblem(t *testing.T) {
+	_, err := comms.NewRequest(api_v1.CommandRun).
+		Args("create", " ").
+		Flag(api_v1.PluginFlag{Name: "name", Shorthand: "n"}).
+		Flag(api_v1.PluginFlag{Name: "name"}).
+		Flag(api_v1.PluginFlag{Name: "namespace", Shorthand: "n"}).
+		Flag(api_v1.PluginFlag{Name: "verbose", Shorthand: "vv"}).
+		Flag(api_v1.PluginFlag{}).
+		Build()
+	var requestErr *comms.RequestError
+	require.ErrorAs(t, err, &requestErr)
+	assert.Equal(t, []string{
+		"arg 1 is empty",
+		`flag "name" is repeated`,
+		`flag "namespace" shorthand "n" is already used`,
+		`flag "verbose" shorthand "vv" must be a single character`,
+		"flag 4 has no name",
+	}, requestErr.Problems)
+}
+
+func TestSend_WhenRequestIsInvalid_DoesNotCallPlugin(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	_, err := comms.Send[TestMsg](context.Background(), plugin, comms.NewRequest("bad"), &e)
+	require.Error(t, err)
+	assert.Empty(t, e.History())
+}
+
+func TestUsage_ReturnsUsageResponse(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput(`{"Version":"v1","Use":"service","Short":"","Long":"","Example":"","Subcommands":["create"],"Flags":null,"RequiresConfig":true}`)
+	usage, err := comms.Usage(context.Background(), plugin, &e)
+	require.NoError(t, err)
+	assert.Equal(t, "service", usage.Use)
+	assert.True(t, usage.RequiresConfig)
+}
+
+func TestConfig_ReturnsConfigResponse(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput(`{"Defaults":[{"Key":"registry","Default":"ghcr.io"}]}`)
+	config, err := comms.Config(context.Background(), plugin, &e)
+	require.NoError(t, err)
+	assert.Equal(t, []api_v1.PluginConfigItem{{Key: "registry", Default: "ghcr.io"}}, config.Defaults)
+}
+
+func TestRun_ReturnsResultOrError(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput(`{"Result":"created","Error":""}`)
+	result, err := comms.Run(context.Background(), plugin, comms.NewRequest(api_v1.CommandRun).Args("create"), &e)
+	require.NoError(t, err)
+	assert.Equal(t, "created", result)
+
+	e.SetOutput(`{"Result":"","Error":"already exists"}`)
+	_, err = comms.Run(context.Background(), plugin, comms.NewRequest(api_v1.CommandRun).Args("create"), &e)
+	require.EqualError(t, err, "already exists")
+}
@@ -0,0 +1,319 @@
+package comms
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/marshal"
+	"github.com/vision-cli/common/plugins"
+)
+
+const (
+	// ServeEnvVar is set to "1" in the environment of plugins started by StartSession.
+	ServeEnvVar = "VISION_PLUGIN_SERVE"
+
+	// sessionCloseGrace is how long Close waits for a plugin to exit after its stdin is closed.
+	sessionCloseGrace = 2 * time.Second
+)
+
+// DefaultStartTimeout is how long StartSession waits for a plugin that advertised serve mode
+// to announce itself before falling back to one-shot calls, unless WithStartTimeout is used.
+const DefaultStartTimeout = 2 * time.Second
+
+// ErrSessionClosed is returned by calls on a session that has been closed.
+var ErrSessionClosed = errors.New("plugin session closed")
+
+// Frames exchanged with a plugin in serve mode, each written as a single line of JSON.
+// Requests and responses in serve mode are always JSON, whatever encoding was negotiated.
+// A plugin that advertises CapabilityServe and is started with ServeEnvVar writes a ServeReady
+// frame first, then answers each ServeRequest on stdin with a ServeResponse carrying the same ID,
+// in any order. The plugin exits when stdin is closed.
+type (
+	ServeReady struct {
+		Serve bool
+	}
+
+	ServeRequest struct {
+		ID      uint64
+		Request json.RawMessage // the request, encoded in the negotiated format
+	}
+
+	ServeResponse struct {
+		ID       uint64
+		Response json.RawMessage // what the plugin would have written to stdout for a one-shot call
+		Error    string          // set when the request could not be handled at all
+	}
+)
+
+// Session is a connection to a plugin that stays running between calls.
+// Calls may be made concurrently. Plugins that do not support serve mode, and plugins run by an
+// executor that is not an execute.Starter, are called one-shot through the session's executor
+// instead.
+type Session struct {
+	plugin   plugins.Plugin
+	executor execute.Executor
+	opts     []CallOption
+	options  *callOptions
+
+	cmd    *exec.Cmd
+	wait   func() error
+	cancel context.CancelFunc
+	stdin  io.WriteCloser
+	stderr *tailBuffer
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan ServeResponse
+	closed  bool
+
+	done chan struct{} // closed once the plugin has exited
+	err  error         // why the plugin exited, valid after done is closed
+}
+
+// StartSession starts plugin in serve mode with executor's Start and waits for it to announce
+// itself. A handshake is made first unless opts include WithProtocol. If the plugin is internal,
+// does not advertise CapabilityServe, does not announce itself within the start timeout (see
+// WithStartTimeout), or exits, or if executor is not an execute.Starter, the returned session
+// falls back to one-shot calls.
+func StartSession(ctx context.Context, plugin plugins.Plugin, executor execute.Executor, opts ...CallOption) (*Session, error) {
+	if executor == nil {
+		return nil, fmt.Errorf("comms.StartSession called with nil executor")
+	}
+	o := newCallOptions(opts)
+	if o.protocol == nil {
+		protocol, err := Handshake(ctx, plugin, executor, opts...)
+		if err != nil {
+			return nil, err
+		}
+		o.protocol = protocol
+		opts = append(opts, WithProtocol(protocol))
+	}
+	s := &Session{plugin: plugin, executor: executor, opts: opts, options: o.framed()}
+	starter, ok := executor.(execute.Starter)
+	if !ok || plugin.InternalCommand != nil || !o.protocol.Supports(CapabilityServe) {
+		return s, nil
+	}
+	o = s.options
+
+	if err := o.verify(plugin); err != nil {
+		return nil, err
+	}
+	processCtx, cancel := context.WithCancel(context.Background())
+	cmd := execute.CommandContext(processCtx, plugin.PluginPath)
+	if err := o.prepare(cmd, ServeEnvVar+"=1"); err != nil {
+		cancel()
+		return nil, fmt.Errorf("cannot run plugin %s: %w", plugin.Name, err)
+	}
+	s.stderr = newTailBuffer(maxStderrTail)
+	cmd.Stderr = s.stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("cannot open stdin of plugin %s: %w", plugin.Name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("cannot open stdout of plugin %s: %w", plugin.Name, err)
+	}
+	wait, err := starter.Start(cmd, o.dir(), "serving plugin "+plugin.Name)
+	if err != nil {
+		cancel()
+		return nil, newPluginError(plugin, "", "", err)
+	}
+
+	reader := bufio.NewReader(stdout)
+	ready := make(chan bool, 1)
+	go func() {
+		line, err := reader.ReadBytes('\n')
+		frame, decodeErr := marshal.Unmarshal[ServeReady](string(line))
+		ready <- err == nil && decodeErr == nil && frame.Serve
+	}()
+
+	timeout := o.start
+	if timeout <= 0 {
+		timeout = DefaultStartTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	serving := false
+	select {
+	case serving = <-ready:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	if !serving {
+		cancel()
+		_ = wait()
+		if ctx.Err() != nil {
+			return nil, &TimeoutError{Plugin: plugin.Name, Err: ctx.Err()}
+		}
+		return s, nil
+	}
+
+	s.cmd = cmd
+	s.wait = wait
+	s.cancel = cancel
+	s.stdin = stdin
+	s.pending = map[uint64]chan ServeResponse{}
+	s.done = make(chan struct{})
+	go s.readResponses(reader)
+	return s, nil
+}
+
+// Serving returns true if calls are multiplexed over a running plugin process.
+func (s *Session) Serving() bool {
+	return s.cmd != nil
+}
+
+// CallSession sends request over session s and decodes the response into T.
+func CallSession[T any](ctx context.Context, s *Session, request *api_v1.PluginRequest) (*T, error) {
+	if request == nil {
+		return nil, fmt.Errorf("comms.CallSession called with nil request")
+	}
+	if !s.Serving() {
+		return CallContext[T](ctx, s.plugin, request, s.executor, s.opts...)
+	}
+	response, err := s.roundTrip(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse[T](s.plugin, response, s.options.codec())
+}
+
+func (s *Session) roundTrip(ctx context.Context, request *api_v1.PluginRequest) (string, error) {
+	query, err := s.options.encode(request)
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal request for plugin %s: %s", s.plugin.Name, err.Error())
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return "", ErrSessionClosed
+	}
+	s.nextID++
+	id := s.nextID
+	reply := make(chan ServeResponse, 1)
+	s.pending[id] = reply
+	s.mu.Unlock()
+
+	if err := s.write(ServeRequest{ID: id, Request: json.RawMessage(query)}); err != nil {
+		s.forget(id)
+		return "", err
+	}
+
+	select {
+	case response := <-reply:
+		if response.Error != "" {
+			return "", errors.New(response.Error)
+		}
+		return string(response.Response), nil
+	case <-s.done:
+		return "", s.err
+	case <-ctx.Done():
+		s.forget(id)
+		return "", &TimeoutError{Plugin: s.plugin.Name, Err: ctx.Err()}
+	}
+}
+
+func (s *Session) write(frame any) error {
+	line, err := marshal.Marshal(frame)
+	if err != nil {
+		return fmt.Errorf("cannot marshal request for plugin %s: %s", s.plugin.Name, err.Error())
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := io.WriteString(s.stdin, line+"\n"); err != nil {
+		return fmt.Errorf("cannot send request to plugin %s: %w", s.plugin.Name, err)
+	}
+	return nil
+}
+
+func (s *Session) forget(id uint64) {
+	s.mu.Lock()
+	delete(s.pending, id)
+	s.mu.Unlock()
+}
+
+// readResponses delivers responses to waiting calls until the plugin closes stdout.
+func (s *Session) readResponses(reader *bufio.Reader) {
+	var readErr error
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			response, decodeErr := marshal.Unmarshal[ServeResponse](string(line))
+			if decodeErr != nil {
+				readErr = fmt.Errorf("cannot unmarshal response from plugin %s: %s", s.plugin.Name, decodeErr.Error())
+				s.cancel()
+				break
+			}
+			s.mu.Lock()
+			reply, ok := s.pending[response.ID]
+			delete(s.pending, response.ID)
+			s.mu.Unlock()
+			if ok {
+				reply <- response
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	_, _ = io.Copy(io.Discard, reader)
+	waitErr := s.wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case readErr != nil:
+		s.err = readErr
+	case s.closed:
+		s.err = ErrSessionClosed
+	case waitErr != nil:
+		s.err = newPluginError(s.plugin, "", s.stderr.String(), waitErr)
+	default:
+		s.err = fmt.Errorf("plugin %s exited during session", s.plugin.Name)
+	}
+	close(s.done)
+}
+
+// Close stops the plugin, waiting briefly for it to exit on its own before killing it.
+// Calls still in flight fail with ErrSessionClosed.
+func (s *Session) Close() error {
+	if !s.Serving() {
+		return nil
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	s.writeMu.Lock()
+	err := s.stdin.Close()
+	s.writeMu.Unlock()
+
+	timer := time.NewTimer(sessionCloseGrace)
+	defer timer.Stop()
+	select {
+	case <-s.done:
+	case <-timer.C:
+		s.cancel()
+		<-s.done
+	}
+	s.cancel()
+	return err
+}
@@ -0,0 +1,170 @@
+package comms_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/comms"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/mocks"
+	"github.com/vision-cli/common/plugins"
+	"github.com/vision-cli/common/tmpl"
+)
+
+func TestSession_WhenPluginServes_ReusesOneProcess(t *testing.T) {
+	plugin := plugins.Plugin{Name: "vision-plugin-helper-v1", PluginPath: helperPlugin(t)}
+	s, err := comms.StartSession(context.Background(), plugin, execute.NewOsExecutor())
+	require.NoError(t, err)
+	defer s.Close()
+	require.True(t, s.Serving())
+
+	first, err := comms.CallSession[TestMsg](context.Background(), s, &api_v1.PluginRequest{Command: "run", Args: []string{"a"}})
+	require.NoError(t, err)
+	second, err := comms.CallSession[TestMsg](context.Background(), s, &api_v1.PluginRequest{Command: "run", Args: []string{"b"}})
+	require.NoError(t, err)
+
+	firstPid := strings.Fields(first.Msg)[1]
+	assert.Equal(t, "pid "+firstPid+" a", first.Msg)
+	assert.Equal(t, "pid "+firstPid+" b", second.Msg)
+}
+
+func TestSession_WhenCallsAreConcurrent_MatchesResponsesToRequests(t *testing.T) {
+	plugin := plugins.Plugin{Name: "vision-plugin-helper-v1", PluginPath: helperPlugin(t)}
+	s, err := comms.StartSession(context.Background(), plugin, execute.NewOsExecutor())
+	require.NoError(t, err)
+	defer s.Close()
+
+	delays := []string{"60ms", "40ms", "20ms", "0ms"}
+	results := make([]string, len(delays))
+	errs := make([]error, len(delays))
+	var wg sync.WaitGroup
+	for i, delay := range delays {
+		wg.Add(1)
+		go func(i int, delay string) {
+			defer wg.Done()
+			request := &api_v1.PluginRequest{Command: "run", Args: []string{delay, "call"}}
+			result, err := comms.CallSession[TestMsg](context.Background(), s, request)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i] = result.Msg
+		}(i, delay)
+	}
+	wg.Wait()
+	for i, delay := range delays {
+		require.NoError(t, errs[i])
+		assert.True(t, strings.HasSuffix(results[i], delay+" call"), results[i])
+	}
+}
+
+func TestSession_WhenPluginDoesNotServe_FallsBackToOneShot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vision-plugin-legacy-v1")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\ncat > /dev/null\necho '{\"Msg\":\"legacy\"}'\n"), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-legacy-v1", PluginPath: path}
+
+	s, err := comms.StartSession(context.Background(), plugin, execute.NewOsExecutor())
+	require.NoError(t, err)
+	defer s.Close()
+	require.False(t, s.Serving())
+
+	result, err := comms.CallSession[TestMsg](context.Background(), s, &pluginRequest)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", result.Msg)
+}
+
+func TestSession_WhenPluginIsInternal_CallsItDirectly(t *testing.T) {
+	plugin := plugins.Plugin{
+		Name: "internal-plugin",
+		InternalCommand: func(_ string, _ execute.Executor, _ tmpl.TmplWriter) string {
+			return `{"Msg":"hello"}`
+		},
+	}
+	e := mocks.NewMockExecutor()
+	s, err := comms.StartSession(context.Background(), plugin, &e)
+	require.NoError(t, err)
+	require.False(t, s.Serving())
+	result, err := comms.CallSession[TestMsg](context.Background(), s, &pluginRequest)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result.Msg)
+	require.NoError(t, s.Close())
+}
+
+func TestSession_WhenClosed_RejectsCalls(t *testing.T) {
+	plugin := plugins.Plugin{Name: "vision-plugin-helper-v1", PluginPath: helperPlugin(t)}
+	s, err := comms.StartSession(context.Background(), plugin, execute.NewOsExecutor())
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	_, err = comms.CallSession[TestMsg](context.Background(), s, &pluginRequest)
+	require.ErrorIs(t, err, comms.ErrSessionClosed)
+}
+
+func TestSession_WhenCallTimesOut_ReturnsTimeoutError(t *testing.T) {
+	plugin := plugins.Plugin{Name: "vision-plugin-helper-v1", PluginPath: helperPlugin(t)}
+	s, err := comms.StartSession(context.Background(), plugin, execute.NewOsExecutor())
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = comms.CallSession[TestMsg](ctx, s, &api_v1.PluginRequest{Command: "run", Args: []string{"200ms"}})
+	var timeoutErr *comms.TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+}
+
+func TestSession_WhenPluginServes_ReportsServeProcessThroughExecutor(t *testing.T) {
+	plugin := plugins.Plugin{Name: "vision-plugin-helper-v1", PluginPath: helperPlugin(t)}
+	var log bytes.Buffer
+	e := execute.OsExecutor{Reporter: execute.LogReporter{Writer: &log}}
+	s, err := comms.StartSession(context.Background(), plugin, e)
+	require.NoError(t, err)
+	require.True(t, s.Serving())
+	assert.Contains(t, log.String(), "serving plugin vision-plugin-helper-v1: start")
+
+	require.NoError(t, s.Close())
+	assert.Contains(t, log.String(), "serving plugin vision-plugin-helper-v1: finish")
+}
+
+func TestSession_WhenExecutorCannotStart_FallsBackToOneShot(t *testing.T) {
+	plugin := plugins.Plugin{Name: "vision-plugin-helper-v1", PluginPath: helperPlugin(t)}
+	recorder := mocks.NewRecordingExecutor(execute.NewOsExecutor())
+	s, err := comms.StartSession(context.Background(), plugin, recorder)
+	require.NoError(t, err)
+	defer s.Close()
+	require.False(t, s.Serving())
+
+	result, err := comms.CallSession[TestMsg](context.Background(), s, &api_v1.PluginRequest{Command: "run", Args: []string{"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, "one-shot a", result.Msg)
+	require.Len(t, recorder.Recording().Exchanges, 2, "the handshake and the call should be recorded")
+}
+
+func TestSession_WithStartTimeout_FallsBackWhenPluginIsSilent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vision-plugin-silent-v1")
+	script := "#!/bin/sh\n" +
+		"if [ \"$" + comms.HandshakeEnvVar + "\" = 1 ]; then\n" +
+		"  cat > /dev/null\n" +
+		"  echo '{\"Versions\":[\"v1\"],\"Capabilities\":[\"serve\"]}'\n" +
+		"  exit 0\n" +
+		"fi\n" +
+		"sleep 5\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-silent-v1", PluginPath: path}
+
+	start := time.Now()
+	s, err := comms.StartSession(context.Background(), plugin, execute.NewOsExecutor(), comms.WithStartTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	defer s.Close()
+	assert.False(t, s.Serving())
+	assert.Less(t, time.Since(start), comms.DefaultStartTimeout)
+}
@@ -0,0 +1,209 @@
+package comms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/marshal"
+	"github.com/vision-cli/common/plugins"
+)
+
+const (
+	// StreamEnvVar is set to "1" in the environment of plugins called with CallStream.
+	StreamEnvVar = "VISION_PLUGIN_STREAM"
+)
+
+// Message types written by a streaming plugin.
+const (
+	MessageProgress = "progress"
+	MessageLog      = "log"
+	MessagePartial  = "partial"
+	MessageResult   = "result"
+	MessageError    = "error"
+)
+
+// Message is a single newline-delimited JSON document written to stdout by a streaming plugin.
+// A stream ends with exactly one result or error message.
+type Message struct {
+	Type     string          // one of the Message constants above
+	Text     string          // progress or log text, or the error of an error message
+	Progress float64         // fraction of the work completed, from 0 to 1, for progress messages
+	Data     json.RawMessage // payload of partial and result messages
+}
+
+// MessageHandler is called in order for each progress, log and partial message of a stream.
+// Returning an error stops the plugin and aborts the call with that error.
+type MessageHandler func(msg Message) error
+
+// CallStream is like CallContext but lets the plugin report progress while it works.
+// The plugin is run with StreamEnvVar set and may write Message documents, one per line, which are
+// passed to handle as they arrive. The Data of the final result message is decoded into T.
+// Plugins that ignore StreamEnvVar and write a single response document are also supported.
+func CallStream[T any](ctx context.Context, plugin plugins.Plugin, request *api_v1.PluginRequest, executor execute.Executor, handle MessageHandler, opts ...CallOption) (*T, error) {
+	if request == nil {
+		return nil, fmt.Errorf("comms.CallStream called with nil request")
+	}
+	if executor == nil {
+		return nil, fmt.Errorf("comms.CallStream called with nil executor")
+	}
+	if handle == nil {
+		return nil, fmt.Errorf("comms.CallStream called with nil handler")
+	}
+	o := newCallOptions(opts).framed()
+	query, err := o.encode(request)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal request for plugin %s: %s", plugin.Name, err.Error())
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := &streamReader{plugin: plugin, codec: o.codec(), handle: handle, stop: cancel}
+
+	if plugin.InternalCommand != nil {
+		response, err := sendInternal(streamCtx, plugin, query, executor)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = stream.Write([]byte(response))
+	} else {
+		if err := ctx.Err(); err != nil {
+			return nil, &TimeoutError{Plugin: plugin.Name, Err: err}
+		}
+		if err := o.verify(plugin); err != nil {
+			return nil, err
+		}
+		cmd, err := newPluginCmd(streamCtx, plugin, query, o, StreamEnvVar+"=1")
+		if err != nil {
+			return nil, err
+		}
+		cmd.Stdout = stream
+		stderr := newTailBuffer(maxStderrTail)
+		cmd.Stderr = stderr
+		err = executor.Errors(cmd, o.dir(), "streaming plugin "+plugin.Name)
+		if stream.err != nil {
+			return nil, stream.err
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, &TimeoutError{Plugin: plugin.Name, Err: ctx.Err()}
+			}
+			return nil, newPluginError(plugin, request.Command, stderr.String(), err)
+		}
+	}
+
+	return decodeStream[T](stream)
+}
+
+func decodeStream[T any](stream *streamReader) (*T, error) {
+	stream.flush()
+	if stream.err != nil {
+		return nil, stream.err
+	}
+	if stream.legacy {
+		return decodeResponse[T](stream.plugin, stream.raw.String(), stream.codec)
+	}
+	if stream.result == nil {
+		return nil, fmt.Errorf("plugin %s ended its stream without a result", stream.plugin.Name)
+	}
+	return decodeResponse[T](stream.plugin, string(stream.result.Data), stream.codec)
+}
+
+// streamReader splits plugin output into messages as it is written.
+// The first non-blank line decides whether the plugin is streaming; if it is not a message,
+// all output is collected in raw as a single legacy response.
+type streamReader struct {
+	plugin  plugins.Plugin
+	codec   marshal.Codec
+	handle  MessageHandler
+	stop    func()
+	pending []byte
+	started bool
+	legacy  bool
+	raw     strings.Builder
+	result  *Message
+	err     error
+}
+
+func (s *streamReader) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.legacy {
+		s.raw.Write(p)
+		return len(p), nil
+	}
+	s.pending = append(s.pending, p...)
+	for !s.legacy {
+		i := bytes.IndexByte(s.pending, '\n')
+		if i < 0 {
+			break
+		}
+		line := s.pending[:i]
+		s.pending = s.pending[i+1:]
+		if err := s.line(line); err != nil {
+			s.err = err
+			s.stop()
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// flush handles any final line that was not terminated by a newline.
+func (s *streamReader) flush() {
+	if s.err != nil || s.legacy || len(s.pending) == 0 {
+		return
+	}
+	line := s.pending
+	s.pending = nil
+	if err := s.line(line); err != nil {
+		s.err = err
+	}
+}
+
+func (s *streamReader) line(line []byte) error {
+	if len(bytes.TrimSpace(line)) == 0 || s.result != nil {
+		return nil
+	}
+	msg, err := marshal.Unmarshal[Message](string(line))
+	if !s.started {
+		if err != nil || !isMessageType(msg.Type) {
+			s.legacy = true
+			s.raw.Write(line)
+			s.raw.WriteByte('\n')
+			s.raw.Write(s.pending)
+			s.pending = nil
+			return nil
+		}
+		s.started = true
+	}
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal message from plugin %s: %s", s.plugin.Name, err.Error())
+	}
+
+	switch msg.Type {
+	case MessageResult:
+		s.result = &msg
+		return nil
+	case MessageError:
+		return errors.New(msg.Text)
+	case MessageProgress, MessageLog, MessagePartial:
+		return s.handle(msg)
+	default:
+		return fmt.Errorf("unknown message type %q from plugin %s", msg.Type, s.plugin.Name)
+	}
+}
+
+func isMessageType(t string) bool {
+	switch t {
+	case MessageProgress, MessageLog, MessagePartial, MessageResult, MessageError:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package comms_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vision-cli/common/comms"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/mocks"
+	"github.com/vision-cli/common/plugins"
+	"github.com/vision-cli/common/tmpl"
+)
+
+func TestCallStream_WhenPluginStreams_HandlesMessagesAndReturnsResult(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput(`{"Type":"progress","Text":"generating","Progress":0.5}
+{"Type":"log","Text":"wrote main.go"}
+{"Type":"partial","Data":{"Msg":"part"}}
+{"Type":"result","Data":{"Msg":"hello"}}
+`)
+	var received []comms.Message
+	result, err := comms.CallStream[TestMsg](context.Background(), plugin, &pluginRequest, &e, func(msg comms.Message) error {
+		received = append(received, msg)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result.Msg)
+	require.Len(t, received, 3)
+	assert.Equal(t, comms.MessageProgress, received[0].Type)
+	assert.Equal(t, 0.5, received[0].Progress)
+	assert.Equal(t, "wrote main.go", received[1].Text)
+	assert.JSONEq(t, `{"Msg":"part"}`, string(received[2].Data))
+}
+
+func TestCallStream_WhenPluginDoesNotStream_DecodesSingleResponse(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput(`{"Msg":"hello"}`)
+	result, err := comms.CallStream[TestMsg](context.Background(), plugin, &pluginRequest, &e, func(comms.Message) error {
+		t.Fatal("handler should not be called")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result.Msg)
+}
+
+func TestCallStream_WhenPluginSendsErrorMessage_ReturnsError(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput(`{"Type":"log","Text":"starting"}
+{"Type":"error","Text":"some error"}
+`)
+	_, err := comms.CallStream[TestMsg](context.Background(), plugin, &pluginRequest, &e, func(comms.Message) error {
+		return nil
+	})
+	require.Error(t, err)
+	assert.Equal(t, "some error", err.Error())
+}
+
+func TestCallStream_WhenStreamHasNoResult_ReturnsError(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput(`{"Type":"log","Text":"starting"}`)
+	_, err := comms.CallStream[TestMsg](context.Background(), plugin, &pluginRequest, &e, func(comms.Message) error {
+		return nil
+	})
+	require.Error(t, err)
+	assert.Equal(t, "plugin plugin ended its stream without a result", err.Error())
+}
+
+func TestCallStream_WhenInternalPluginStreams_HandlesMessages(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	plugin := plugins.Plugin{
+		Name: "internal-plugin",
+		InternalCommand: func(_ string, _ execute.Executor, _ tmpl.TmplWriter) string {
+			return "{\"Type\":\"log\",\"Text\":\"working\"}\n{\"Type\":\"result\",\"Data\":{\"Msg\":\"hello\"}}\n"
+		},
+	}
+	var texts []string
+	result, err := comms.CallStream[TestMsg](context.Background(), plugin, &pluginRequest, &e, func(msg comms.Message) error {
+		texts = append(texts, msg.Text)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result.Msg)
+	assert.Equal(t, []string{"working"}, texts)
+}
+
+func TestCallStream_WhenHandlerFails_StopsPluginAndReturnsHandlerError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vision-plugin-stream-v1")
+	script := "#!/bin/sh\n" +
+		"[ \"$" + comms.StreamEnvVar + "\" = 1 ] || exit 9\n" +
+		"echo '{\"Type\":\"progress\",\"Text\":\"step 1\"}'\n" +
+		"sleep 30\n" +
+		"echo '{\"Type\":\"result\",\"Data\":{\"Msg\":\"hello\"}}'\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-stream-v1", PluginPath: path}
+
+	stop := errors.New("stop")
+	_, err := comms.CallStream[TestMsg](context.Background(), plugin, &pluginRequest, execute.NewOsExecutor(), func(msg comms.Message) error {
+		assert.Equal(t, "step 1", msg.Text)
+		return stop
+	})
+	require.ErrorIs(t, err, stop)
+}
+
+func TestCallStream_WhenPluginFails_ReturnsPluginErrorWithStderr(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vision-plugin-stream-broken-v1")
+	script := "#!/bin/sh\n" +
+		"echo '{\"Type\":\"progress\",\"Text\":\"step 1\"}'\n" +
+		"echo config missing >&2\n" +
+		"exit 3\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-stream-broken-v1", PluginPath: path}
+
+	_, err := comms.CallStream[TestMsg](context.Background(), plugin, &pluginRequest, execute.NewOsExecutor(), func(comms.Message) error {
+		return nil
+	})
+	var pluginErr *comms.PluginError
+	require.ErrorAs(t, err, &pluginErr)
+	assert.Equal(t, 3, pluginErr.ExitCode)
+	assert.Equal(t, "config missing\n", pluginErr.Stderr)
+}
@@ -0,0 +1,25 @@
+package execute
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+const (
+	// waitDelay bounds how long Wait blocks on output pipes after a cancelled command is killed.
+	waitDelay = 5 * time.Second
+)
+
+// CommandContext returns a command like exec.CommandContext, except that the command is started
+// in its own process group and the whole group is killed when ctx is done, so that any children
+// the command spawned do not outlive it.
+func CommandContext(ctx context.Context, name string, arg ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, arg...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = waitDelay
+	return cmd
+}
@@ -0,0 +1,105 @@
+package execute
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// DefaultGrace is how long a cancelled command has to exit after SIGTERM before it is killed,
+// unless OsExecutor.Grace is set.
+const DefaultGrace = 5 * time.Second
+
+// ExecutorContext is an Executor whose commands stop when a context is done.
+// Commands are stopped with SIGTERM, then SIGKILL after a grace period, sent to the command's
+// whole process group so that anything it started stops too.
+type ExecutorContext interface {
+	Runner
+	// ErrorsContext is like Errors but stops the command when ctx is done.
+	ErrorsContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) error
+	// OutputContext is like Output but stops the command when ctx is done.
+	OutputContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) (string, error)
+	// RunContext is like Run but stops the command when ctx is done.
+	RunContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) (Result, error)
+}
+
+// Starter is an Executor that can start a command without waiting for it to finish, so that the
+// command can be fed stdin while it runs. Executors that read the whole of a command's stdin
+// before running it, such as those that record or replay commands, do not implement it.
+type Starter interface {
+	Executor
+	// Start starts cmd and returns a function that waits for it to finish, reporting errors as
+	// Errors does.
+	Start(cmd *exec.Cmd, targetDir string, action string) (wait func() error, err error)
+}
+
+// CancelledError is returned when a command is stopped because its context is done, as opposed
+// to failing by itself. Use errors.Is with context.Canceled or context.DeadlineExceeded to tell
+// why.
+type CancelledError struct {
+	Action  string
+	Command string
+	Err     error // the context error
+}
+
+func (e *CancelledError) Error() string {
+	return fmt.Sprintf("%s: command %q cancelled: %s", e.Action, e.Command, e.Err.Error())
+}
+
+func (e *CancelledError) Unwrap() error {
+	return e.Err
+}
+
+// start starts cmd and, if ctx can be done, stops its process group when it is. Commands whose
+// context can never be done stay in the caller's process group, so they still receive signals
+// from the terminal. The returned function waits for cmd, reporting a *CancelledError if it was
+// stopped.
+func (e OsExecutor) start(ctx context.Context, cmd *exec.Cmd, action string) (func() error, error) {
+	if ctx.Done() == nil {
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return cmd.Wait, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, &CancelledError{Action: action, Command: cmd.String(), Err: err}
+	}
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-exited:
+			return
+		case <-ctx.Done():
+		}
+		_ = terminateProcessGroup(cmd)
+		timer := time.NewTimer(e.grace())
+		defer timer.Stop()
+		select {
+		case <-exited:
+		case <-timer.C:
+			_ = killProcessGroup(cmd)
+		}
+	}()
+
+	return func() error {
+		err := cmd.Wait()
+		close(exited)
+		if err != nil && ctx.Err() != nil {
+			return &CancelledError{Action: action, Command: cmd.String(), Err: ctx.Err()}
+		}
+		return err
+	}, nil
+}
+
+func (e OsExecutor) grace() time.Duration {
+	if e.Grace > 0 {
+		return e.Grace
+	}
+	return DefaultGrace
+}
@@ -0,0 +1,181 @@
+package execute
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Command is a command recorded by a DryRunExecutor.
+type Command struct {
+	Action string
+	Dir    string
+	Args   []string // the program as given and its arguments
+	Env    []string // variables set or changed from the current environment, or all of them if Clean
+	Clean  bool     // the command does not inherit variables removed from the current environment
+	Stdin  string
+}
+
+// ErrDryRun is returned by DryRunExecutor.Output, which has no output to return. Callers that
+// need the output, such as comms.Call, return it wrapped; use errors.Is to tell it apart.
+var ErrDryRun = errors.New("no output in a dry run")
+
+// DryRunExecutor implements Executor and ExecutorContext without running anything. It records
+// each command so that they can be reviewed, or written as a shell script and run later.
+// Errors and Run succeed with no output, and Output returns ErrDryRun once the command is
+// recorded.
+type DryRunExecutor struct {
+	mu       sync.Mutex
+	commands []Command
+}
+
+// NewDryRunExecutor returns an executor that records commands instead of running them.
+func NewDryRunExecutor() *DryRunExecutor {
+	return &DryRunExecutor{}
+}
+
+func (e *DryRunExecutor) Errors(cmd *exec.Cmd, targetDir string, action string) error {
+	return e.record(cmd, targetDir, action)
+}
+
+func (e *DryRunExecutor) ErrorsContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) error {
+	return e.Errors(cmd, targetDir, action)
+}
+
+func (e *DryRunExecutor) Output(cmd *exec.Cmd, targetDir string, action string) (string, error) {
+	if err := e.record(cmd, targetDir, action); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s: %w", action, ErrDryRun)
+}
+
+func (e *DryRunExecutor) OutputContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) (string, error) {
+	return e.Output(cmd, targetDir, action)
+}
+
+func (e *DryRunExecutor) Run(cmd *exec.Cmd, targetDir string, action string) (Result, error) {
+	if err := e.record(cmd, targetDir, action); err != nil {
+		return Result{Command: cmd.String(), ExitCode: -1}, err
+	}
+	return Result{Command: cmd.String()}, nil
+}
+
+func (e *DryRunExecutor) RunContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) (Result, error) {
+	return e.Run(cmd, targetDir, action)
+}
+
+// CommandExists returns true, so that a dry run carries on as if every command is installed.
+func (e *DryRunExecutor) CommandExists(cmd string) bool {
+	return true
+}
+
+// Commands returns the commands recorded so far, in order.
+func (e *DryRunExecutor) Commands() []Command {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Command{}, e.commands...)
+}
+
+// Script returns the commands recorded so far as a shell script that runs them in order,
+// stopping at the first that fails.
+func (e *DryRunExecutor) Script() string {
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\nset -e\n")
+	for _, c := range e.Commands() {
+		fmt.Fprintf(&script, "\n# %s\n%s\n", c.Action, c.shell())
+	}
+	return script.String()
+}
+
+// WriteScript writes the script returned by Script to w.
+func (e *DryRunExecutor) WriteScript(w io.Writer) error {
+	_, err := io.WriteString(w, e.Script())
+	return err
+}
+
+func (e *DryRunExecutor) record(cmd *exec.Cmd, targetDir string, action string) error {
+	c := Command{Action: action, Dir: targetDir, Args: append([]string{}, cmd.Args...)}
+	c.Env, c.Clean = environment(cmd.Env)
+	if cmd.Stdin != nil {
+		data, err := io.ReadAll(cmd.Stdin)
+		if err != nil {
+			return fmt.Errorf("%s: reading stdin of %q: %w", action, cmd.String(), err)
+		}
+		cmd.Stdin = bytes.NewReader(data)
+		c.Stdin = string(data)
+	}
+	e.mu.Lock()
+	e.commands = append(e.commands, c)
+	e.mu.Unlock()
+	return nil
+}
+
+// shell returns c as one line of shell, run in a subshell so that its directory does not carry
+// over to the next command.
+func (c Command) shell() string {
+	words := make([]string, 0, len(c.Env)+len(c.Args))
+	for _, v := range c.Env {
+		name, value, _ := strings.Cut(v, "=")
+		words = append(words, name+"="+quote(value))
+	}
+	for _, a := range c.Args {
+		words = append(words, quote(a))
+	}
+	line := strings.Join(words, " ")
+	if c.Clean {
+		line = "env -i " + line
+	}
+	if c.Stdin != "" {
+		line = fmt.Sprintf("printf '%%s' %s | %s", quote(c.Stdin), line)
+	}
+	if c.Dir != "" {
+		line = fmt.Sprintf("(cd %s && %s)", quote(c.Dir), line)
+	}
+	return line
+}
+
+// environment returns how env differs from the current environment. If env removes any variable,
+// all of env is returned, to be set in a clean environment. Otherwise only the entries that are
+// not in the current environment are returned. A nil env inherits the current environment.
+func environment(env []string) ([]string, bool) {
+	if env == nil {
+		return nil, false
+	}
+	names := map[string]bool{}
+	for _, v := range env {
+		name, _, _ := strings.Cut(v, "=")
+		names[name] = true
+	}
+	current := map[string]bool{}
+	for _, v := range os.Environ() {
+		name, _, _ := strings.Cut(v, "=")
+		if !names[name] {
+			return append([]string{}, env...), true
+		}
+		current[v] = true
+	}
+	var changed []string
+	for _, v := range env {
+		if !current[v] {
+			changed = append(changed, v)
+		}
+	}
+	return changed, false
+}
+
+var safeWord = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// quote returns s as a single shell word.
+func quote(s string) string {
+	if safeWord.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
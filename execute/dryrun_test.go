@@ -0,0 +1,87 @@
+package execute_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/comms"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/plugins"
+	"github.com/vision-cli/common/workspace"
+)
+
+func TestDryRunExecutor_RecordsCommandsWithoutRunningThem(t *testing.T) {
+	dir := t.TempDir()
+	e := execute.NewDryRunExecutor()
+	err := workspace.Use(dir, "./service", e)
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, filepath.Join(dir, "go.work"))
+	assert.Equal(t, []execute.Command{
+		{Action: "initialising workspace", Dir: dir, Args: []string{"go", "work", "init"}},
+		{Action: "updating workspace modules", Dir: dir, Args: []string{"go", "work", "use", "-r", "./service"}},
+	}, e.Commands())
+}
+
+func TestDryRunExecutor_Script_QuotesArgsEnvAndStdin(t *testing.T) {
+	e := execute.NewDryRunExecutor()
+	cmd := exec.Command("plugin", "it's")
+	cmd.Env = append(os.Environ(), "ENCODING=json lines")
+	cmd.Stdin = strings.NewReader(`{"command":"info"}`)
+	out, err := e.Output(cmd, "/tmp/my dir", "calling plugin")
+	require.ErrorIs(t, err, execute.ErrDryRun)
+	assert.Empty(t, out)
+
+	stdin, _ := cmd.Stdin.(interface{ Len() int })
+	assert.Equal(t, len(`{"command":"info"}`), stdin.Len(), "stdin should still be readable")
+	assert.Equal(t, "#!/bin/sh\nset -e\n\n"+
+		"# calling plugin\n"+
+		`(cd '/tmp/my dir' && printf '%s' '{"command":"info"}' | ENCODING='json lines' plugin 'it'\''s')`+"\n",
+		e.Script())
+}
+
+func TestDryRunExecutor_Script_ReproducesCommands(t *testing.T) {
+	dir := t.TempDir()
+	e := execute.NewDryRunExecutor()
+	cmd := exec.Command("sh", "-c", "cat > 'out file'")
+	cmd.Stdin = strings.NewReader("it's here\n")
+	_, err := e.Run(cmd, dir, "writing")
+	require.NoError(t, err)
+	require.NoFileExists(t, filepath.Join(dir, "out file"))
+
+	var script strings.Builder
+	require.NoError(t, e.WriteScript(&script))
+	require.NoError(t, exec.Command("sh", "-c", script.String()).Run())
+	written, err := os.ReadFile(filepath.Join(dir, "out file"))
+	require.NoError(t, err)
+	assert.Equal(t, "it's here\n", string(written))
+}
+
+func TestDryRunExecutor_Script_WithPolicy_DoesNotInheritRemovedVariables(t *testing.T) {
+	t.Setenv("SECRET_TOKEN", "secret")
+	report := filepath.Join(t.TempDir(), "env")
+	path := filepath.Join(t.TempDir(), "vision-plugin-env-v1")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\nenv > "+report+"\n"), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-env-v1", PluginPath: path}
+
+	e := execute.NewDryRunExecutor()
+	policy := comms.Policy{Env: []string{"PATH"}}
+	_, err := comms.Call[api_v1.PluginResponse](plugin, &api_v1.PluginRequest{Command: api_v1.CommandRun}, e, comms.WithPolicy(policy))
+	require.ErrorIs(t, err, execute.ErrDryRun, "a dry run has no response to decode")
+	require.Len(t, e.Commands(), 1)
+	assert.True(t, e.Commands()[0].Clean)
+	assert.NotContains(t, e.Script(), "SECRET_TOKEN")
+	assert.Contains(t, e.Script(), "env -i PATH=")
+
+	require.NoError(t, exec.Command("sh", "-c", e.Script()).Run())
+	env, err := os.ReadFile(report)
+	require.NoError(t, err)
+	assert.NotContains(t, string(env), "SECRET_TOKEN")
+	assert.Contains(t, string(env), "PATH=")
+}
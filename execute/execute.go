@@ -1,13 +1,14 @@
 package execute
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"time"
-
-	"github.com/briandowns/spinner"
 )
 
 const (
@@ -15,7 +16,7 @@ const (
 )
 
 type Executor interface {
-	// Errors writes command errors to stderr during execution.
+	// Errors writes command errors to stderr during execution, and also to cmd.Stderr if it is set.
 	Errors(cmd *exec.Cmd, targetDir string, action string) error
 	// Output returns the output of the command as a string.
 	Output(cmd *exec.Cmd, targetDir string, action string) (string, error)
@@ -27,50 +28,110 @@ func NewOsExecutor() Executor {
 	return OsExecutor{}
 }
 
-// OsExecutor implements Executor using the os/exec package.
-type OsExecutor struct{}
+// OsExecutor implements Executor, ExecutorContext and Starter using the os/exec package.
+type OsExecutor struct {
+	Grace    time.Duration // how long cancelled commands have between SIGTERM and SIGKILL, DefaultGrace if zero
+	Mode     OutputMode    // how running commands are shown, if Reporter is nil
+	Log      io.Writer     // where PlainOutput and JSONOutput lines are written, os.Stderr if nil
+	Reporter Reporter      // shows running commands, overriding Mode
+}
 
 // Action string is used to log command info and wrap any returned errors
-func (OsExecutor) Errors(cmd *exec.Cmd, targetDir string, action string) error {
+func (e OsExecutor) Errors(cmd *exec.Cmd, targetDir string, action string) error {
+	return e.ErrorsContext(context.Background(), cmd, targetDir, action)
+}
+
+func (e OsExecutor) ErrorsContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) (err error) {
 	cmd.Dir = targetDir
+	finish := e.reporter().Start(action, cmd)
+	defer func() { finish(err) }()
+
+	stderr := io.Writer(os.Stderr)
+	if cmd.Stderr != nil {
+		stderr = io.MultiWriter(os.Stderr, cmd.Stderr)
+		cmd.Stderr = nil
+	}
 	cmdErr, err := cmd.StderrPipe()
 	if err != nil {
 		return fmt.Errorf("%s: piping standard error for %q: %w", action, cmd.String(), err)
 	}
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("%s: executing command %q: %w", action, cmd.String(), err)
+	wait, err := e.start(ctx, cmd, action)
+	if err != nil {
+		return wrap(err, "%s: executing command %q", action, cmd)
 	}
 
-	s := spinner.New(spinner.CharSets[9], timeConstant*time.Millisecond)
-	s.Prefix = fmt.Sprintf("%s: Waiting for command %q ", action, cmd.String())
-	s.Start()
-
-	if _, err := io.Copy(os.Stderr, cmdErr); err != nil {
+	if _, err := io.Copy(stderr, cmdErr); err != nil {
 		fmt.Fprintf(os.Stderr, "error copying command stderr\n")
 	}
 
-	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("%s: command %q finished with error: %w", action, cmd.String(), err)
+	if err := wait(); err != nil {
+		return wrap(err, "%s: command %q finished with error", action, cmd)
 	}
-
-	s.Stop()
 	return nil
 }
 
-func (OsExecutor) Output(cmd *exec.Cmd, targetDir string, action string) (string, error) {
+func (e OsExecutor) Start(cmd *exec.Cmd, targetDir string, action string) (func() error, error) {
+	cmd.Dir = targetDir
+	finish := e.reporter().Start(action, cmd)
+	wait, err := e.start(context.Background(), cmd, action)
+	if err != nil {
+		err = wrap(err, "%s: executing command %q", action, cmd)
+		finish(err)
+		return nil, err
+	}
+	return func() error {
+		err := wait()
+		if err != nil {
+			err = wrap(err, "%s: command %q finished with error", action, cmd)
+		}
+		finish(err)
+		return err
+	}, nil
+}
+
+func (e OsExecutor) Output(cmd *exec.Cmd, targetDir string, action string) (string, error) {
+	return e.OutputContext(context.Background(), cmd, targetDir, action)
+}
+
+func (e OsExecutor) OutputContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) (_ string, err error) {
 	cmd.Dir = targetDir
+	if cmd.Stdout != nil {
+		return "", fmt.Errorf("%s: executing command %q: %w", action, cmd.String(), errors.New("exec: Stdout already set"))
+	}
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	// keep stderr for the *exec.ExitError, as cmd.Output does
+	var stderr *bytes.Buffer
+	if cmd.Stderr == nil {
+		stderr = &bytes.Buffer{}
+		cmd.Stderr = stderr
+	}
 
-	s := spinner.New(spinner.CharSets[9], timeConstant*time.Millisecond)
-	s.Prefix = fmt.Sprintf("%s: Waiting for command %q ", action, cmd.String())
-	s.Start()
+	finish := e.reporter().Start(action, cmd)
+	defer func() { finish(err) }()
 
-	output, err := cmd.Output()
+	wait, err := e.start(ctx, cmd, action)
 	if err != nil {
-		return "", fmt.Errorf("%s: executing command %q: %w", action, cmd.String(), err)
+		return "", wrap(err, "%s: executing command %q", action, cmd)
+	}
+	if err := wait(); err != nil {
+		var exitErr *exec.ExitError
+		if stderr != nil && errors.As(err, &exitErr) {
+			exitErr.Stderr = stderr.Bytes()
+		}
+		return "", wrap(err, "%s: executing command %q", action, cmd)
+	}
+	return stdout.String(), nil
+}
+
+// wrap adds action and the command line to err, unless it reports a cancellation.
+func wrap(err error, format string, action string, cmd *exec.Cmd) error {
+	var cancelled *CancelledError
+	if errors.As(err, &cancelled) {
+		return err
 	}
-	s.Stop()
-	return string(output), nil
+	return fmt.Errorf(format+": %w", action, cmd.String(), err)
 }
 
 func (OsExecutor) CommandExists(cmd string) bool {
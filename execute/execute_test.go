@@ -1,8 +1,13 @@
 package execute_test
 
 import (
+	"context"
+	"errors"
+	"io"
 	"os/exec"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -42,3 +47,139 @@ func TestErrors_ForUnexecutable_ReturnsErrors(t *testing.T) {
 	err := e.Errors(cmd, ".", "testing")
 	require.Error(t, err)
 }
+
+func TestCommandContext_WhenContextExpires_KillsChildProcesses(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cmd := execute.CommandContext(ctx, "sh", "-c", "sleep 30 & wait")
+	start := time.Now()
+	err := cmd.Run()
+	require.Error(t, err)
+	assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestOutputContext_WhenContextExpires_ReturnsCancelledError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	e := execute.OsExecutor{}
+	start := time.Now()
+	_, err := e.OutputContext(ctx, exec.Command("sh", "-c", "sleep 30 & wait"), ".", "testing")
+
+	var cancelled *execute.CancelledError
+	require.ErrorAs(t, err, &cancelled)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, "testing", cancelled.Action)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestOutputContext_WhenCommandIgnoresSIGTERM_KillsItAfterGrace(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	e := execute.OsExecutor{Grace: 200 * time.Millisecond}
+	start := time.Now()
+	_, err := e.OutputContext(ctx, exec.Command("sh", "-c", "trap '' TERM; sleep 30 & wait"), ".", "testing")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestErrorsContext_WhenCommandHandlesSIGTERM_LetsItExit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	e := execute.OsExecutor{Grace: 10 * time.Second}
+	cmd := exec.Command("sh", "-c", "trap 'echo cleaned up; exit 1' TERM; sleep 30 & wait")
+	var stdout strings.Builder
+	cmd.Stdout = &stdout
+	start := time.Now()
+	err := e.ErrorsContext(ctx, cmd, ".", "testing")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, "cleaned up\n", stdout.String())
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestOutputContext_WhenCommandFails_ReturnsCommandError(t *testing.T) {
+	e := execute.OsExecutor{}
+	_, err := e.OutputContext(context.Background(), exec.Command("sh", "-c", "echo problem >&2; exit 2"), ".", "testing")
+
+	var cancelled *execute.CancelledError
+	assert.False(t, errors.As(err, &cancelled))
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 2, exitErr.ExitCode())
+	assert.Equal(t, "problem\n", string(exitErr.Stderr))
+}
+
+func TestErrorsContext_WhenAlreadyCancelled_DoesNotStartCommand(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cmd := exec.Command("true")
+	err := execute.OsExecutor{}.ErrorsContext(ctx, cmd, ".", "testing")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, cmd.Process)
+}
+
+func TestRun_ReturnsOutputAndExitCode(t *testing.T) {
+	e := execute.OsExecutor{}
+	result, err := e.Run(exec.Command("sh", "-c", "echo out; sleep 0.1; echo err >&2; exit 3"), ".", "testing")
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, "out\n", result.Stdout)
+	assert.Equal(t, "err\n", result.Stderr)
+	assert.Equal(t, "out\nerr\n", result.Combined)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Contains(t, result.Command, "sh -c echo out")
+	assert.Greater(t, result.Duration, time.Duration(0))
+	assert.Equal(t, "err\n", string(exitErr.Stderr))
+}
+
+func TestRun_WhenCommandSucceeds_ReturnsZeroExitCode(t *testing.T) {
+	e := execute.OsExecutor{}
+	var stdout strings.Builder
+	cmd := exec.Command("ls", "execute_test.go")
+	cmd.Stdout = &stdout
+	result, err := e.Run(cmd, ".", "testing")
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "execute_test.go\n", result.Stdout)
+	assert.Equal(t, "execute_test.go\n", stdout.String(), "output should still reach the command's own stdout")
+}
+
+func TestRun_ForUnexecutable_ReturnsError(t *testing.T) {
+	e := execute.OsExecutor{}
+	result, err := e.Run(exec.Command("execute_test.go"), ".", "testing")
+	require.Error(t, err)
+	assert.Equal(t, -1, result.ExitCode)
+}
+
+func TestRunWith_WhenExecutorIsNotRunner_FillsResultFromErrors(t *testing.T) {
+	e := struct{ execute.Executor }{execute.OsExecutor{Mode: execute.PlainOutput, Log: io.Discard}}
+	result, err := execute.RunWith(e, exec.Command("sh", "-c", "echo out; sleep 0.1; echo err >&2; exit 3"), ".", "testing")
+	require.Error(t, err)
+	assert.Equal(t, "out\n", result.Stdout)
+	assert.Equal(t, "err\n", result.Stderr)
+	assert.Equal(t, "out\nerr\n", result.Combined)
+	assert.Equal(t, 3, result.ExitCode)
+}
+
+func TestErrors_WhenStderrIsSet_AlsoWritesToIt(t *testing.T) {
+	e := execute.NewOsExecutor()
+	var stderr strings.Builder
+	cmd := exec.Command("sh", "-c", "echo problem >&2; exit 1")
+	cmd.Stderr = &stderr
+	err := e.Errors(cmd, ".", "testing")
+	require.Error(t, err)
+	assert.Equal(t, "problem\n", stderr.String())
+}
+
+func TestStart_ReturnsWaitThatReportsExitError(t *testing.T) {
+	var e execute.Starter = execute.OsExecutor{}
+	wait, err := e.Start(exec.Command("sh", "-c", "exit 4"), ".", "testing")
+	require.NoError(t, err)
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, wait(), &exitErr)
+	assert.Equal(t, 4, exitErr.ExitCode())
+}
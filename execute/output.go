@@ -0,0 +1,59 @@
+package execute
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// OutputMode selects how an OsExecutor without a Reporter shows the commands it runs.
+type OutputMode int
+
+const (
+	// AutoOutput shows a spinner when stdout is a terminal, and plain lines otherwise or when the
+	// CI environment variable is set.
+	AutoOutput OutputMode = iota
+	// SpinnerOutput shows a spinner while each command runs.
+	SpinnerOutput
+	// PlainOutput writes a timestamped line when each command starts and finishes.
+	PlainOutput
+	// JSONOutput writes an Event as a line of JSON when each command starts and finishes.
+	JSONOutput
+)
+
+// Event is written by a LogReporter in JSON mode, as used by an OsExecutor in JSONOutput mode.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"` // "start" or "finish"
+	Action     string    `json:"action"`
+	Command    string    `json:"command"`
+	Dir        string    `json:"dir,omitempty"`
+	DurationMs int64     `json:"durationMs,omitempty"` // set on finish
+	Error      string    `json:"error,omitempty"`      // set on finish if the command failed
+}
+
+// reporter returns the Reporter for e, choosing one from e.Mode if e.Reporter is nil.
+func (e OsExecutor) reporter() Reporter {
+	if e.Reporter != nil {
+		return e.Reporter
+	}
+	switch e.mode() {
+	case PlainOutput:
+		return LogReporter{Writer: e.Log}
+	case JSONOutput:
+		return LogReporter{Writer: e.Log, JSON: true}
+	default:
+		return SpinnerReporter{}
+	}
+}
+
+func (e OsExecutor) mode() OutputMode {
+	if e.Mode != AutoOutput {
+		return e.Mode
+	}
+	if os.Getenv("CI") == "" && term.IsTerminal(int(os.Stdout.Fd())) {
+		return SpinnerOutput
+	}
+	return PlainOutput
+}
@@ -0,0 +1,65 @@
+package execute_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vision-cli/common/execute"
+)
+
+func TestPlainOutput_WritesTimestampedStartAndFinishLines(t *testing.T) {
+	var log bytes.Buffer
+	e := execute.OsExecutor{Mode: execute.PlainOutput, Log: &log}
+	_, err := e.Output(exec.Command("ls", "output_test.go"), ".", "listing")
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^\d{4}-\d\d-\d\dT\S+ listing: start ".*ls output_test.go"$`, lines[0])
+	assert.Regexp(t, `^\d{4}-\d\d-\d\dT\S+ listing: finish ".*ls output_test.go" after \S+$`, lines[1])
+}
+
+func TestPlainOutput_WhenCommandFails_IncludesError(t *testing.T) {
+	var log bytes.Buffer
+	e := execute.OsExecutor{Mode: execute.PlainOutput, Log: &log}
+	err := e.Errors(exec.Command("sh", "-c", "exit 2"), ".", "failing")
+	require.Error(t, err)
+	assert.Contains(t, log.String(), "with error: "+err.Error())
+}
+
+func TestJSONOutput_WritesEventPerLine(t *testing.T) {
+	var log bytes.Buffer
+	e := execute.OsExecutor{Mode: execute.JSONOutput, Log: &log}
+	_, err := e.Run(exec.Command("sh", "-c", "exit 1"), ".", "failing")
+	require.Error(t, err)
+
+	var events []execute.Event
+	dec := json.NewDecoder(&log)
+	for dec.More() {
+		var ev execute.Event
+		require.NoError(t, dec.Decode(&ev))
+		events = append(events, ev)
+	}
+	require.Len(t, events, 2)
+	assert.Equal(t, "start", events[0].Event)
+	assert.Equal(t, "failing", events[0].Action)
+	assert.Contains(t, events[0].Command, "sh -c exit 1")
+	assert.Empty(t, events[0].Error)
+	assert.Equal(t, "finish", events[1].Event)
+	assert.Equal(t, err.Error(), events[1].Error)
+	assert.False(t, events[1].Time.Before(events[0].Time))
+}
+
+func TestAutoOutput_WhenNotATerminal_WritesPlainLines(t *testing.T) {
+	t.Setenv("CI", "true")
+	var log bytes.Buffer
+	e := execute.OsExecutor{Log: &log}
+	_, err := e.Output(exec.Command("ls", "output_test.go"), ".", "listing")
+	require.NoError(t, err)
+	assert.Contains(t, log.String(), "listing: start")
+}
@@ -0,0 +1,24 @@
+//go:build !unix
+
+package execute
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on platforms without process groups.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcessGroup kills cmd's process, since it cannot be asked to exit on this platform.
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return killProcessGroup(cmd)
+}
+
+// killProcessGroup kills cmd's process. Children are not tracked on this platform.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return os.ErrProcessDone
+	}
+	return cmd.Process.Kill()
+}
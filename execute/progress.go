@@ -0,0 +1,179 @@
+package execute
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/briandowns/spinner"
+)
+
+// Reporter shows the progress of the commands run by an OsExecutor. Start is called just before
+// cmd starts, and the function it returns is called once cmd has finished, with the error the
+// executor returns. Reporters may be shared by commands running concurrently.
+type Reporter interface {
+	Start(action string, cmd *exec.Cmd) func(err error)
+}
+
+// SpinnerReporter shows a spinner while each command runs. The spinner is only drawn when Writer
+// is a terminal.
+type SpinnerReporter struct {
+	CharSet []string      // spinner.CharSets[9] if nil
+	Delay   time.Duration // time between frames, 100ms if zero
+	Format  string        // prefix format, given the action and command line
+	Writer  *os.File      // os.Stdout if nil
+}
+
+const defaultSpinnerFormat = "%s: Waiting for command %q "
+
+func (r SpinnerReporter) Start(action string, cmd *exec.Cmd) func(err error) {
+	s := r.spinner()
+	format := r.Format
+	if format == "" {
+		format = defaultSpinnerFormat
+	}
+	s.Prefix = fmt.Sprintf(format, action, cmd.String())
+	s.Start()
+	return func(error) { s.Stop() }
+}
+
+func (r SpinnerReporter) spinner() *spinner.Spinner {
+	charSet, delay, writer := r.CharSet, r.Delay, r.Writer
+	if charSet == nil {
+		charSet = spinner.CharSets[9]
+	}
+	if delay == 0 {
+		delay = timeConstant * time.Millisecond
+	}
+	if writer == nil {
+		writer = os.Stdout
+	}
+	return spinner.New(charSet, delay, spinner.WithWriterFile(writer))
+}
+
+// LogReporter writes a timestamped line when each command starts and finishes.
+type LogReporter struct {
+	Writer io.Writer // os.Stderr if nil
+	JSON   bool      // write each Event as a line of JSON instead of plain text
+}
+
+func (r LogReporter) Start(action string, cmd *exec.Cmd) func(err error) {
+	start := Event{Time: time.Now(), Event: "start", Action: action, Command: cmd.String(), Dir: cmd.Dir}
+	r.log(start)
+	return func(err error) {
+		finish := start
+		finish.Time = time.Now()
+		finish.Event = "finish"
+		finish.DurationMs = finish.Time.Sub(start.Time).Milliseconds()
+		if err != nil {
+			finish.Error = err.Error()
+		}
+		r.log(finish)
+	}
+}
+
+// log writes ev as one line, so that lines from concurrent commands do not interleave.
+func (r LogReporter) log(ev Event) {
+	var line bytes.Buffer
+	if r.JSON {
+		_ = json.NewEncoder(&line).Encode(ev)
+	} else {
+		fmt.Fprintf(&line, "%s %s: %s %q", ev.Time.Format(time.RFC3339), ev.Action, ev.Event, ev.Command)
+		if ev.Event == "finish" {
+			fmt.Fprintf(&line, " after %s", time.Duration(ev.DurationMs)*time.Millisecond)
+		}
+		if ev.Error != "" {
+			fmt.Fprintf(&line, " with error: %s", ev.Error)
+		}
+		line.WriteByte('\n')
+	}
+	w := r.Writer
+	if w == nil {
+		w = os.Stderr
+	}
+	_, _ = w.Write(line.Bytes())
+}
+
+// SilentReporter shows nothing.
+type SilentReporter struct{}
+
+func (SilentReporter) Start(string, *exec.Cmd) func(error) {
+	return func(error) {}
+}
+
+// MultiReporter shows concurrent commands in one display: a single spinner listing every running
+// command, with a line written above it as each command finishes.
+type MultiReporter struct {
+	mu      sync.Mutex
+	spinner *spinner.Spinner
+	writer  io.Writer
+	running []*task // in the order they started
+}
+
+type task struct {
+	name  string
+	start time.Time
+}
+
+// NewMultiReporter returns a MultiReporter that draws on w, or os.Stdout if w is nil.
+// The spinner is only drawn when w is a terminal, but finished lines are always written.
+func NewMultiReporter(w *os.File) *MultiReporter {
+	s := SpinnerReporter{Writer: w}.spinner()
+	return &MultiReporter{spinner: s, writer: s.Writer}
+}
+
+func (r *MultiReporter) Start(action string, cmd *exec.Cmd) func(err error) {
+	t := &task{name: action, start: time.Now()}
+	if cmd.Dir != "" {
+		t.name = fmt.Sprintf("%s (%s)", action, cmd.Dir)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running = append(r.running, t)
+	r.redraw()
+	return func(err error) { r.finish(t, err) }
+}
+
+func (r *MultiReporter) finish(t *task, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, running := range r.running {
+		if running == t {
+			r.running = append(r.running[:i], r.running[i+1:]...)
+			break
+		}
+	}
+	r.spinner.Stop()
+	line := fmt.Sprintf("%s: finished after %s", t.name, time.Since(t.start).Round(time.Millisecond))
+	if err != nil {
+		line += fmt.Sprintf(" with error: %s", err)
+	}
+	fmt.Fprintln(r.writer, line)
+	r.redraw()
+}
+
+// redraw shows the running commands, or stops the spinner if there are none. r.mu must be held.
+func (r *MultiReporter) redraw() {
+	if len(r.running) == 0 {
+		r.spinner.Stop()
+		return
+	}
+	names := make([]string, len(r.running))
+	for i, t := range r.running {
+		names[i] = t.name
+	}
+	r.spinner.Lock()
+	if len(names) == 1 {
+		r.spinner.Prefix = fmt.Sprintf("Waiting for %s ", names[0])
+	} else {
+		r.spinner.Prefix = fmt.Sprintf("Waiting for %d commands: %s ", len(names), strings.Join(names, ", "))
+	}
+	r.spinner.Unlock()
+	r.spinner.Start()
+}
@@ -0,0 +1,118 @@
+package execute_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vision-cli/common/execute"
+)
+
+type recordingReporter struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recordingReporter) Start(action string, cmd *exec.Cmd) func(err error) {
+	r.add("start " + action + " in " + cmd.Dir)
+	return func(err error) {
+		if err != nil {
+			r.add("finish " + action + ": " + err.Error())
+		} else {
+			r.add("finish " + action)
+		}
+	}
+}
+
+func (r *recordingReporter) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestReporter_IsToldWhenCommandsStartAndFinish(t *testing.T) {
+	r := &recordingReporter{}
+	e := execute.OsExecutor{Reporter: r, Mode: execute.JSONOutput}
+	_, err := e.Output(exec.Command("ls", "progress_test.go"), ".", "listing")
+	require.NoError(t, err)
+	err = e.Errors(exec.Command("sh", "-c", "exit 1"), ".", "failing")
+	require.Error(t, err)
+
+	assert.Equal(t, []string{
+		"start listing in .",
+		"finish listing",
+		"start failing in .",
+		"finish failing: " + err.Error(),
+	}, r.events)
+}
+
+func TestLogReporter_WithJSON_IncludesDir(t *testing.T) {
+	var log bytes.Buffer
+	e := execute.OsExecutor{Reporter: execute.LogReporter{Writer: &log, JSON: true}}
+	_, err := e.Run(exec.Command("ls"), "..", "listing")
+	require.NoError(t, err)
+
+	var ev execute.Event
+	require.NoError(t, json.NewDecoder(&log).Decode(&ev))
+	assert.Equal(t, "..", ev.Dir)
+}
+
+func TestSilentReporter_WritesNothing(t *testing.T) {
+	var log bytes.Buffer
+	e := execute.OsExecutor{Reporter: execute.SilentReporter{}, Mode: execute.PlainOutput, Log: &log}
+	_, err := e.Output(exec.Command("ls"), ".", "listing")
+	require.NoError(t, err)
+	assert.Empty(t, log.String())
+}
+
+func TestSpinnerReporter_WhenNotATerminal_WritesNothing(t *testing.T) {
+	out, err := os.CreateTemp(t.TempDir(), "spinner")
+	require.NoError(t, err)
+	defer out.Close()
+	e := execute.OsExecutor{Reporter: execute.SpinnerReporter{Writer: out}}
+	_, err = e.Output(exec.Command("sleep", "0.2"), ".", "sleeping")
+	require.NoError(t, err)
+
+	written, err := os.ReadFile(out.Name())
+	require.NoError(t, err)
+	assert.Empty(t, written)
+}
+
+func TestMultiReporter_WritesLineAsEachConcurrentCommandFinishes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "multi")
+	out, err := os.Create(path)
+	require.NoError(t, err)
+	defer out.Close()
+	e := execute.OsExecutor{Reporter: execute.NewMultiReporter(out)}
+
+	var wg sync.WaitGroup
+	for _, dir := range []string{".", ".."} {
+		dir := dir
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := e.Run(exec.Command("sleep", "0.1"), dir, "sleeping")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	_, err = e.Run(exec.Command("sh", "-c", "exit 1"), ".", "failing")
+	require.Error(t, err)
+
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(written)), "\n")
+	require.Len(t, lines, 3)
+	assert.ElementsMatch(t, []string{"sleeping (.)", "sleeping (..)"}, []string{
+		strings.SplitN(lines[0], ":", 2)[0],
+		strings.SplitN(lines[1], ":", 2)[0],
+	})
+	assert.Regexp(t, `^failing \(\.\): finished after \S+ with error: `, lines[2])
+}
@@ -0,0 +1,115 @@
+package execute
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Result is everything a command run by Run wrote and how it ended.
+type Result struct {
+	Command  string // the command line, with the program resolved from the path
+	Stdout   string
+	Stderr   string
+	Combined string // stdout and stderr interleaved in the order they were written
+	ExitCode int    // -1 if the command did not start or was killed by a signal
+	Duration time.Duration
+}
+
+// Runner is an Executor that can also return everything a command wrote and how it ended.
+type Runner interface {
+	Executor
+	// Run returns everything the command wrote and how it ended. The result is filled in as far
+	// as possible even when an error is returned.
+	Run(cmd *exec.Cmd, targetDir string, action string) (Result, error)
+}
+
+// RunWith runs cmd with executor.Run if executor is a Runner. Otherwise it runs cmd with
+// executor.Errors and fills in the result from what the command wrote to cmd.Stdout and
+// cmd.Stderr and from the error.
+func RunWith(executor Executor, cmd *exec.Cmd, targetDir string, action string) (Result, error) {
+	if runner, ok := executor.(Runner); ok {
+		return runner.Run(cmd, targetDir, action)
+	}
+	result := Result{Command: cmd.String()}
+	var stdout, stderr, combined bytes.Buffer
+	var mu sync.Mutex
+	cmd.Stdout = teeWriter(cmd.Stdout, &stdout, &combined, &mu)
+	cmd.Stderr = teeWriter(cmd.Stderr, &stderr, &combined, &mu)
+	start := time.Now()
+	err := executor.Errors(cmd, targetDir, action)
+	result.Duration = time.Since(start)
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	result.Combined = combined.String()
+	if err != nil {
+		result.ExitCode = -1
+		var exited interface{ ExitCode() int }
+		if errors.As(err, &exited) {
+			result.ExitCode = exited.ExitCode()
+		}
+	}
+	return result, err
+}
+
+func (e OsExecutor) Run(cmd *exec.Cmd, targetDir string, action string) (Result, error) {
+	return e.RunContext(context.Background(), cmd, targetDir, action)
+}
+
+func (e OsExecutor) RunContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) (_ Result, err error) {
+	cmd.Dir = targetDir
+	result := Result{Command: cmd.String(), ExitCode: -1}
+	var stdout, stderr, combined bytes.Buffer
+	var mu sync.Mutex
+	cmd.Stdout = teeWriter(cmd.Stdout, &stdout, &combined, &mu)
+	cmd.Stderr = teeWriter(cmd.Stderr, &stderr, &combined, &mu)
+
+	finish := e.reporter().Start(action, cmd)
+	defer func() { finish(err) }()
+
+	start := time.Now()
+	wait, err := e.start(ctx, cmd, action)
+	if err == nil {
+		err = wait()
+	}
+	result.Duration = time.Since(start)
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	result.Combined = combined.String()
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitErr.Stderr = stderr.Bytes()
+		}
+		return result, wrap(err, "%s: command %q finished with error", action, cmd)
+	}
+	return result, nil
+}
+
+// teeWriter returns a writer that writes to out, to own and, under mu, to combined.
+// out may be nil.
+func teeWriter(out io.Writer, own *bytes.Buffer, combined *bytes.Buffer, mu *sync.Mutex) io.Writer {
+	w := &lockedWriter{mu: mu, w: io.MultiWriter(own, combined)}
+	if out == nil {
+		return w
+	}
+	return io.MultiWriter(w, out)
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
@@ -14,6 +14,7 @@ var Osopen = os.Open
 var Osremoveall = os.RemoveAll
 var Osgetwd = os.Getwd
 var Osgetenv = os.Getenv
+var Osreadfile = os.ReadFile
 
 // CreateDir creates a directory, along with any necessary parents.
 // If path is already a file that is not a directory,
@@ -102,3 +103,8 @@ func GetEnv(key string) string {
 func Open(name string) (*os.File, error) {
 	return Osopen(name)
 }
+
+// Wrapper around os.ReadFile
+func ReadFile(name string) ([]byte, error) {
+	return Osreadfile(name)
+}
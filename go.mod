@@ -2,12 +2,15 @@ module github.com/vision-cli/common
 
 go 1.20
 
-require github.com/briandowns/spinner v1.23.0
+require (
+	github.com/briandowns/spinner v1.23.0
+	github.com/fxamacker/cbor/v2 v2.5.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/openconfig/goyang v1.4.0 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
@@ -16,9 +19,10 @@ require (
 	github.com/mattn/go-colorable v0.1.2 // indirect
 	github.com/mattn/go-isatty v0.0.8 // indirect
 	github.com/oshothebig/pbast v0.0.0-20170925213915-84cdd26c3def
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/stretchr/testify v1.8.4
 	github.com/vision-cli/api v0.3.0
 	golang.org/x/sys v0.1.0 // indirect
-	golang.org/x/term v0.1.0 // indirect
+	golang.org/x/term v0.1.0
 	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,122 @@
+package marshal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Names of the built in codecs.
+const (
+	JSON        = "json"         // JSON that rejects unknown fields, like Unmarshal
+	JSONLenient = "json-lenient" // JSON that ignores unknown fields
+	YAML        = "yaml"         // YAML that rejects unknown fields
+	CBOR        = "cbor"         // compact binary encoding (RFC 8949) that rejects unknown fields
+)
+
+// Codec encodes and decodes values in one format.
+type Codec interface {
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{}
+)
+
+func init() {
+	cborDecode, err := cbor.DecOptions{ExtraReturnErrors: cbor.ExtraDecErrorUnknownField}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	cborEncode, err := cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		panic(err)
+	}
+	RegisterCodec(jsonCodec{name: JSON, strict: true})
+	RegisterCodec(jsonCodec{name: JSONLenient})
+	RegisterCodec(yamlCodec{})
+	RegisterCodec(cborCodec{enc: cborEncode, dec: cborDecode})
+}
+
+// RegisterCodec makes c available by name, replacing any codec of the same name.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.Name()] = c
+}
+
+// CodecFor returns the codec called name.
+func CodecFor(name string) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown codec %q", name)
+	}
+	return c, nil
+}
+
+// CodecNames returns the names of the registered codecs in alphabetical order.
+func CodecNames() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MarshalWith is like Marshal but encodes data with codec.
+func MarshalWith[T any](codec Codec, data T) (string, error) {
+	out, err := codec.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// UnmarshalWith is like Unmarshal but decodes data with codec.
+func UnmarshalWith[T any](codec Codec, data string) (T, error) {
+	var resp T
+	err := codec.Unmarshal([]byte(data), &resp)
+	return resp, err
+}
+
+type jsonCodec struct {
+	name   string
+	strict bool
+}
+
+func (c jsonCodec) Name() string { return c.name }
+
+func (c jsonCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (c jsonCodec) Unmarshal(data []byte, v any) error { return decodeJSON(data, v, c.strict) }
+
+type yamlCodec struct{}
+
+func (yamlCodec) Name() string { return YAML }
+
+func (yamlCodec) Marshal(v any) ([]byte, error) { return yaml.Marshal(v) }
+
+func (yamlCodec) Unmarshal(data []byte, v any) error { return yaml.UnmarshalStrict(data, v) }
+
+type cborCodec struct {
+	enc cbor.EncMode
+	dec cbor.DecMode
+}
+
+func (cborCodec) Name() string { return CBOR }
+
+func (c cborCodec) Marshal(v any) ([]byte, error) { return c.enc.Marshal(v) }
+
+func (c cborCodec) Unmarshal(data []byte, v any) error { return c.dec.Unmarshal(data, v) }
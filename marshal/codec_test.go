@@ -0,0 +1,96 @@
+package marshal_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/marshal"
+)
+
+type extendedResponse struct {
+	Result string
+	Error  string
+	Extra  string
+}
+
+func TestCodecs_RoundTripRequest(t *testing.T) {
+	request := api_v1.PluginRequest{
+		Command: api_v1.CommandRun,
+		Args:    []string{"create", "service"},
+		Flags:   []api_v1.PluginFlag{{Name: "force", Shorthand: "f", Value: "true"}},
+	}
+	for _, name := range []string{marshal.JSON, marshal.JSONLenient, marshal.YAML, marshal.CBOR} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := marshal.CodecFor(name)
+			require.NoError(t, err)
+			assert.Equal(t, name, codec.Name())
+
+			data, err := marshal.MarshalWith(codec, request)
+			require.NoError(t, err)
+			decoded, err := marshal.UnmarshalWith[api_v1.PluginRequest](codec, data)
+			require.NoError(t, err)
+			assert.Equal(t, request, decoded)
+		})
+	}
+}
+
+func TestCodecs_WhenStrict_RejectUnknownFields(t *testing.T) {
+	for _, name := range []string{marshal.JSON, marshal.YAML, marshal.CBOR} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := marshal.CodecFor(name)
+			require.NoError(t, err)
+			data, err := marshal.MarshalWith(codec, extendedResponse{Result: "ok", Extra: "new"})
+			require.NoError(t, err)
+
+			_, err = marshal.UnmarshalWith[api_v1.PluginResponse](codec, data)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCodecs_JSONLenient_IgnoresUnknownFields(t *testing.T) {
+	codec, err := marshal.CodecFor(marshal.JSONLenient)
+	require.NoError(t, err)
+
+	result, err := marshal.UnmarshalWith[api_v1.PluginResponse](codec, `{"Result":"ok","Extra":"new"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result.Result)
+}
+
+func TestCodecs_CBOR_IsSmallerThanJSON(t *testing.T) {
+	request := api_v1.PluginRequest{Command: api_v1.CommandRun, Args: []string{"create", "service"}}
+	jsonCodec, err := marshal.CodecFor(marshal.JSON)
+	require.NoError(t, err)
+	cborCodec, err := marshal.CodecFor(marshal.CBOR)
+	require.NoError(t, err)
+
+	asJSON, err := marshal.MarshalWith(jsonCodec, request)
+	require.NoError(t, err)
+	asCBOR, err := marshal.MarshalWith(cborCodec, request)
+	require.NoError(t, err)
+	assert.Less(t, len(asCBOR), len(asJSON))
+}
+
+func TestCodecFor_WithUnknownName_ReturnsError(t *testing.T) {
+	_, err := marshal.CodecFor("xml")
+	assert.EqualError(t, err, `unknown codec "xml"`)
+}
+
+type upperCodec struct {
+	marshal.Codec
+}
+
+func (upperCodec) Name() string { return "test-upper" }
+
+func TestRegisterCodec_MakesCodecAvailableByName(t *testing.T) {
+	json, err := marshal.CodecFor(marshal.JSON)
+	require.NoError(t, err)
+	marshal.RegisterCodec(upperCodec{Codec: json})
+
+	codec, err := marshal.CodecFor("test-upper")
+	require.NoError(t, err)
+	assert.Equal(t, "test-upper", codec.Name())
+	assert.Contains(t, marshal.CodecNames(), "test-upper")
+}
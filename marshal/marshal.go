@@ -3,17 +3,29 @@ package marshal
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 )
 
+// ErrTrailingData is returned when a JSON document is followed by anything but whitespace.
+var ErrTrailingData = errors.New("trailing data after JSON document")
+
+// Unmarshal decodes a single JSON document, rejecting unknown fields and trailing data.
 func Unmarshal[T any](data string) (T, error) {
 	var resp T
-	dec := json.NewDecoder(bytes.NewReader([]byte(data)))
-	dec.DisallowUnknownFields() // Force errors
-	err := dec.Decode(&resp)
-	if err != nil {
-		return resp, err
+	err := decodeJSON([]byte(data), &resp, true)
+	return resp, err
+}
+
+// UnmarshalLenient is like Unmarshal but ignores unknown fields, returning their JSON pointer
+// paths (such as "/Flags/0/Extra") so they can be reported as warnings.
+func UnmarshalLenient[T any](data string) (T, []string, error) {
+	var resp T
+	if err := decodeJSON([]byte(data), &resp, false); err != nil {
+		return resp, nil, err
 	}
-	return resp, nil
+	unknown, err := UnknownFields[T](data)
+	return resp, unknown, err
 }
 
 func Marshal[T any](data T) (string, error) {
@@ -23,3 +35,19 @@ func Marshal[T any](data T) (string, error) {
 	}
 	return string(respStr), nil
 }
+
+// decodeJSON decodes the JSON document in data into v and checks that nothing follows it.
+func decodeJSON(data []byte, v any, strict bool) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields() // Force errors
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	offset := dec.InputOffset()
+	if len(bytes.TrimSpace(data[offset:])) > 0 {
+		return fmt.Errorf("%w at offset %d", ErrTrailingData, offset)
+	}
+	return nil
+}
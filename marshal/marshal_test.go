@@ -49,3 +49,61 @@ func TestMarshal_WithInValidObject_ReturnsError(t *testing.T) {
 	_, err := marshal.Marshal[float64](math.Inf(1))
 	assert.Equal(t, "json: unsupported value: +Inf", err.Error())
 }
+
+func TestUnmarshal_WithTrailingDocument_ReturnsError(t *testing.T) {
+	req := `{"Result":"first","Error":""} {"Result":"second","Error":""}`
+	_, err := marshal.Unmarshal[api_v1.PluginResponse](req)
+	require.ErrorIs(t, err, marshal.ErrTrailingData)
+	assert.Equal(t, "trailing data after JSON document at offset 29", err.Error())
+}
+
+func TestUnmarshal_WithTrailingWhitespace_ReturnsObject(t *testing.T) {
+	result, err := marshal.Unmarshal[api_v1.PluginResponse]("{\"Result\":\"result\",\"Error\":\"\"}\n\n")
+	require.NoError(t, err)
+	assert.Equal(t, "result", result.Result)
+}
+
+func TestUnmarshalLenient_WithUnknownFields_ReturnsObjectAndPaths(t *testing.T) {
+	req := `{
+		"Command": "run",
+		"Args": ["create"],
+		"Flags": [{"Name": "force", "Shorthand": "f", "Value": "true", "Default": "false"}],
+		"Placeholders": {"ProjectName": "p", "Extra/Info": {"Nested": 1}},
+		"Version": "v2"
+	}`
+	result, unknown, err := marshal.UnmarshalLenient[api_v1.PluginRequest](req)
+	require.NoError(t, err)
+	assert.Equal(t, "run", result.Command)
+	assert.Equal(t, "force", result.Flags[0].Name)
+	assert.Equal(t, "p", result.Placeholders.ProjectName)
+	assert.Equal(t, []string{"/Flags/0/Default", "/Placeholders/Extra~1Info", "/Version"}, unknown)
+}
+
+func TestUnmarshalLenient_WithKnownFields_ReturnsNoPaths(t *testing.T) {
+	_, unknown, err := marshal.UnmarshalLenient[api_v1.PluginResponse](`{"result":"r"}`)
+	require.NoError(t, err)
+	assert.Empty(t, unknown)
+}
+
+func TestUnmarshalLenient_WithTrailingData_ReturnsError(t *testing.T) {
+	_, _, err := marshal.UnmarshalLenient[api_v1.PluginResponse](`{"Result":"r"}x`)
+	assert.ErrorIs(t, err, marshal.ErrTrailingData)
+}
+
+type tagged struct {
+	Renamed string            `json:"renamed"`
+	Ignored string            `json:"-"`
+	Values  map[string]nested `json:"values"`
+	nested
+}
+
+type nested struct {
+	Inner string
+}
+
+func TestUnknownFields_RespectsTagsMapsAndEmbeddedStructs(t *testing.T) {
+	doc := `{"renamed":"a","Ignored":"b","Inner":"c","values":{"k":{"Inner":"d","Other":"e"}}}`
+	unknown, err := marshal.UnknownFields[tagged](doc)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/Ignored", "/values/k/Other"}, unknown)
+}
@@ -0,0 +1,343 @@
+package marshal
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SchemaDialect is the JSON Schema version of generated schemas.
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+)
+
+// Schema is the subset of JSON Schema needed to describe the JSON encoding of Go types.
+type Schema struct {
+	Dialect    string             // $schema, set on the root of generated schemas
+	Types      []string           // allowed JSON types, any type if empty
+	Format     string             // format of strings, such as "date-time"
+	Properties map[string]*Schema // schemas of object properties
+	Required   []string           // properties that must be present
+	Closed     bool               // if true, properties other than Properties are not allowed
+	Additional *Schema            // schema of properties other than Properties, if not Closed
+	Items      *Schema            // schema of array items
+}
+
+// SchemaFor returns the schema of the JSON encoding of T.
+// Struct fields are named by their json tags. Like Unmarshal, the schema does not require fields
+// to be present unless they are tagged `jsonschema:"required"`. Structs are closed, as Unmarshal
+// rejects unknown fields. Nil pointers, slices and maps encode as null, so their schemas allow
+// null.
+func SchemaFor[T any]() *Schema {
+	s := schemaOf(reflect.TypeOf((*T)(nil)).Elem(), map[reflect.Type]bool{})
+	s.Dialect = SchemaDialect
+	return s
+}
+
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+	s := &Schema{}
+	switch {
+	case t == timeType:
+		s.Types = []string{"string"}
+		s.Format = "date-time"
+		return withNull(s, nullable)
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// the encoding is up to the type
+		return s
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		// encoding/json writes the text as a string
+		s.Types = []string{"string"}
+		return withNull(s, nullable)
+	case seen[t]:
+		// recursive types are described by their outer schema only
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		s.Types = []string{"boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s.Types = []string{"integer"}
+	case reflect.Float32, reflect.Float64:
+		s.Types = []string{"number"}
+	case reflect.String:
+		s.Types = []string{"string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			s.Types = []string{"string"}
+			s.Format = "byte"
+			return withNull(s, true)
+		}
+		s.Types = []string{"array"}
+		s.Items = schemaOf(t.Elem(), seen)
+		nullable = true
+	case reflect.Array:
+		s.Types = []string{"array"}
+		s.Items = schemaOf(t.Elem(), seen)
+	case reflect.Map:
+		s.Types = []string{"object"}
+		s.Additional = schemaOf(t.Elem(), seen)
+		nullable = true
+	case reflect.Struct:
+		seen[t] = true
+		s.Types = []string{"object"}
+		s.Properties = map[string]*Schema{}
+		s.Closed = true
+		addFields(s, t, seen)
+		sort.Strings(s.Required)
+		delete(seen, t)
+	}
+	return withNull(s, nullable)
+}
+
+// addFields adds the properties of struct t, including those promoted from embedded structs.
+func addFields(s *Schema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			addFields(s, ft, seen)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := s.Properties[name]; ok {
+			continue
+		}
+		s.Properties[name] = schemaOf(f.Type, seen)
+		if hasOption(f.Tag.Get("jsonschema"), "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func hasOption(opts string, option string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func withNull(s *Schema, nullable bool) *Schema {
+	if nullable && len(s.Types) > 0 {
+		s.Types = append(s.Types, "null")
+	}
+	return s
+}
+
+// MarshalJSON writes s as a JSON Schema document.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	doc := map[string]any{}
+	if s.Dialect != "" {
+		doc["$schema"] = s.Dialect
+	}
+	switch len(s.Types) {
+	case 0:
+	case 1:
+		doc["type"] = s.Types[0]
+	default:
+		doc["type"] = s.Types
+	}
+	if s.Format != "" {
+		doc["format"] = s.Format
+	}
+	if s.Properties != nil {
+		doc["properties"] = s.Properties
+	}
+	if len(s.Required) > 0 {
+		doc["required"] = s.Required
+	}
+	if s.Closed {
+		doc["additionalProperties"] = false
+	} else if s.Additional != nil {
+		doc["additionalProperties"] = s.Additional
+	}
+	if s.Items != nil {
+		doc["items"] = s.Items
+	}
+	return json.Marshal(doc)
+}
+
+// Violation is a way in which a document does not match a schema.
+type Violation struct {
+	Path    string // JSON pointer to the offending value, empty for the whole document
+	Message string
+}
+
+func (v Violation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s: %s", path, v.Message)
+}
+
+// ValidationError is returned when a document does not match its schema.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		lines[i] = v.String()
+	}
+	return fmt.Sprintf("document does not match schema:\n%s", strings.Join(lines, "\n"))
+}
+
+// Validate checks the JSON document data against s and returns every violation found.
+// It returns an error only if data is not JSON.
+func (s *Schema) Validate(data string) ([]Violation, error) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	offset := dec.InputOffset()
+	if len(bytes.TrimSpace([]byte(data[offset:]))) > 0 {
+		return nil, fmt.Errorf("%w at offset %d", ErrTrailingData, offset)
+	}
+	var violations []Violation
+	s.validate(doc, "", &violations)
+	return violations, nil
+}
+
+func (s *Schema) validate(doc any, path string, violations *[]Violation) {
+	if len(s.Types) > 0 && !s.allows(doc) {
+		*violations = append(*violations, Violation{
+			Path:    path,
+			Message: fmt.Sprintf("expected %s, got %s", strings.Join(s.Types, " or "), jsonType(doc)),
+		})
+		return
+	}
+	switch doc := doc.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if !hasKey(doc, name) {
+				*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf("missing required property %q", name)})
+			}
+		}
+		keys := make([]string, 0, len(doc))
+		for k := range doc {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := path + "/" + PointerToken(k)
+			if p, ok := s.property(k); ok {
+				p.validate(doc[k], child, violations)
+			} else if s.Closed {
+				*violations = append(*violations, Violation{Path: child, Message: "unknown property"})
+			} else if s.Additional != nil {
+				s.Additional.validate(doc[k], child, violations)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, v := range doc {
+				s.Items.validate(v, fmt.Sprintf("%s/%d", path, i), violations)
+			}
+		}
+	}
+}
+
+// property finds the schema of property name, matching case as encoding/json does.
+func (s *Schema) property(name string) (*Schema, bool) {
+	if p, ok := s.Properties[name]; ok {
+		return p, true
+	}
+	for k, p := range s.Properties {
+		if strings.EqualFold(k, name) {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+func hasKey(doc map[string]any, name string) bool {
+	if _, ok := doc[name]; ok {
+		return true
+	}
+	for k := range doc {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) allows(doc any) bool {
+	actual := jsonType(doc)
+	for _, t := range s.Types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type of a value decoded with UseNumber.
+func jsonType(doc any) string {
+	switch doc := doc.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		// decide from the literal, so that integers beyond the range of int64 are still integers
+		if strings.ContainsAny(doc.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// UnmarshalValidated is like Unmarshal but first validates data against the schema of T,
+// returning a *ValidationError listing every violation instead of only the first decode error.
+func UnmarshalValidated[T any](data string) (T, error) {
+	var resp T
+	violations, err := SchemaFor[T]().Validate(data)
+	if err != nil {
+		return resp, err
+	}
+	if len(violations) > 0 {
+		return resp, &ValidationError{Violations: violations}
+	}
+	return Unmarshal[T](data)
+}
@@ -0,0 +1,144 @@
+package marshal_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/marshal"
+)
+
+type schemaExample struct {
+	Name     string            `json:"name" jsonschema:"required"`
+	Count    int               `json:"count,omitempty"`
+	Ratio    float64           `json:"ratio" jsonschema:"required"`
+	Enabled  *bool             `json:"enabled"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Created  time.Time         `json:"created"`
+	Internal string            `json:"-"`
+	Child    *schemaExample    `json:"child,omitempty"`
+}
+
+func TestSchemaFor_DescribesJSONEncoding(t *testing.T) {
+	schema, err := json.Marshal(marshal.SchemaFor[schemaExample]())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["name", "ratio"],
+		"properties": {
+			"name": {"type": "string"},
+			"count": {"type": "integer"},
+			"ratio": {"type": "number"},
+			"enabled": {"type": ["boolean", "null"]},
+			"tags": {"type": ["array", "null"], "items": {"type": "string"}},
+			"labels": {"type": ["object", "null"], "additionalProperties": {"type": "string"}},
+			"created": {"type": "string", "format": "date-time"},
+			"child": {}
+		}
+	}`, string(schema))
+}
+
+func TestSchemaFor_IncludesPromotedFields(t *testing.T) {
+	schema := marshal.SchemaFor[tagged]()
+	assert.Contains(t, schema.Properties, "Inner")
+	assert.Contains(t, schema.Properties, "renamed")
+	assert.NotContains(t, schema.Properties, "Ignored")
+}
+
+func TestValidate_WithMatchingDocument_ReturnsNoViolations(t *testing.T) {
+	doc := `{"name":"a","ratio":1,"enabled":null,"tags":["x"],"created":"2024-01-01T00:00:00Z"}`
+	violations, err := marshal.SchemaFor[schemaExample]().Validate(doc)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestValidate_ReturnsEveryViolation(t *testing.T) {
+	doc := `{"name":1,"count":1.5,"tags":["x",2],"labels":{"a/b":3},"created":"now","extra":true}`
+	violations, err := marshal.SchemaFor[schemaExample]().Validate(doc)
+	require.NoError(t, err)
+	assert.Equal(t, []marshal.Violation{
+		{Path: "", Message: `missing required property "ratio"`},
+		{Path: "/count", Message: "expected integer, got number"},
+		{Path: "/extra", Message: "unknown property"},
+		{Path: "/labels/a~1b", Message: "expected string, got integer"},
+		{Path: "/name", Message: "expected string, got integer"},
+		{Path: "/tags/1", Message: "expected string, got integer"},
+	}, violations)
+}
+
+func TestValidate_WithInvalidJSON_ReturnsError(t *testing.T) {
+	_, err := marshal.SchemaFor[schemaExample]().Validate(`{"name":`)
+	assert.Error(t, err)
+}
+
+func TestUnmarshalValidated_WithViolations_ReturnsValidationError(t *testing.T) {
+	_, err := marshal.UnmarshalValidated[api_v1.PluginResponse](`{"Result":1,"Other":""}`)
+	var validation *marshal.ValidationError
+	require.ErrorAs(t, err, &validation)
+	assert.Equal(t, "document does not match schema:\n"+
+		"/Other: unknown property\n"+
+		"/Result: expected string, got integer", err.Error())
+}
+
+func TestUnmarshalValidated_WithValidDocument_ReturnsObject(t *testing.T) {
+	result, err := marshal.UnmarshalValidated[api_v1.PluginResponse](`{"result":"r","error":""}`)
+	require.NoError(t, err)
+	assert.Equal(t, "r", result.Result)
+}
+
+func TestUnmarshalValidated_WithPartialDocument_AcceptsWhatUnmarshalAccepts(t *testing.T) {
+	doc := `{"Error":"boom"}`
+	_, err := marshal.Unmarshal[api_v1.PluginResponse](doc)
+	require.NoError(t, err)
+
+	result, err := marshal.UnmarshalValidated[api_v1.PluginResponse](doc)
+	require.NoError(t, err)
+	assert.Equal(t, "boom", result.Error)
+}
+
+func TestValidate_WithRequiredTag_ReportsMissingProperty(t *testing.T) {
+	violations, err := marshal.SchemaFor[schemaExample]().Validate(`{"name":"a"}`)
+	require.NoError(t, err)
+	assert.Equal(t, []marshal.Violation{{Path: "", Message: `missing required property "ratio"`}}, violations)
+}
+
+type level int
+
+func (l level) MarshalText() ([]byte, error) {
+	return []byte([]string{"low", "high"}[l]), nil
+}
+
+func TestSchemaFor_DescribesTextMarshalersAsStrings(t *testing.T) {
+	type example struct {
+		Level level  `json:"level"`
+		Max   *level `json:"max"`
+	}
+	schema, err := json.Marshal(marshal.SchemaFor[example]().Properties)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"level": {"type": "string"}, "max": {"type": ["string", "null"]}}`, string(schema))
+
+	doc, err := json.Marshal(example{Level: 1})
+	require.NoError(t, err)
+	violations, err := marshal.SchemaFor[example]().Validate(string(doc))
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestValidate_WithIntegerBeyondInt64_AcceptsIt(t *testing.T) {
+	type example struct {
+		Size uint64 `json:"size"`
+	}
+	violations, err := marshal.SchemaFor[example]().Validate(`{"size":18446744073709551615}`)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	violations, err = marshal.SchemaFor[example]().Validate(`{"size":1.5}`)
+	require.NoError(t, err)
+	assert.Len(t, violations, 1)
+}
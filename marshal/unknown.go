@@ -0,0 +1,121 @@
+package marshal
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// UnknownFields returns the JSON pointer paths of the fields in the JSON document data that
+// have no matching field in T, in document order of their parents and alphabetical order within
+// each object. Field names are matched as encoding/json matches them, respecting json tags and
+// ignoring case.
+func UnknownFields[T any](data string) ([]string, error) {
+	var doc any
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, err
+	}
+	var unknown []string
+	collectUnknown(doc, reflect.TypeOf((*T)(nil)).Elem(), "", &unknown)
+	return unknown, nil
+}
+
+func collectUnknown(doc any, t reflect.Type, path string, unknown *[]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return
+	}
+	switch doc := doc.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(doc))
+		for k := range doc {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for _, k := range keys {
+				field, ok := lookupField(fields, k)
+				if !ok {
+					*unknown = append(*unknown, path+"/"+PointerToken(k))
+					continue
+				}
+				collectUnknown(doc[k], field, path+"/"+PointerToken(k), unknown)
+			}
+		case reflect.Map:
+			for _, k := range keys {
+				collectUnknown(doc[k], t.Elem(), path+"/"+PointerToken(k), unknown)
+			}
+		}
+	case []any:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+		for i, v := range doc {
+			collectUnknown(v, t.Elem(), path+"/"+strconv.Itoa(i), unknown)
+		}
+	}
+}
+
+// jsonFields returns the types of the fields encoding/json decodes into t, by JSON name,
+// including fields promoted from embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for k, v := range jsonFields(ft) {
+				if _, ok := fields[k]; !ok {
+					fields[k] = v
+				}
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// lookupField finds key in fields, preferring an exact match as encoding/json does.
+func lookupField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if t, ok := fields[key]; ok {
+		return t, true
+	}
+	for name, t := range fields {
+		if strings.EqualFold(name, key) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// PointerToken escapes s for use as one reference token of a JSON pointer (RFC 6901).
+func PointerToken(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
@@ -1,18 +1,29 @@
 package mocks
 
 import (
+	"context"
+	"io"
 	"os/exec"
+
+	"github.com/vision-cli/common/execute"
 )
 
 type MockExecutor struct {
 	history   []string
 	cmds      map[string]string
 	output    string
+	stderr    string
 	outputErr error
 }
 
+// Errors records action and writes any output set with SetOutput to cmd.Stdout, if it has one.
 func (e *MockExecutor) Errors(cmd *exec.Cmd, targetDir string, action string) error {
 	e.history = append(e.history, action)
+	if cmd.Stdout != nil && e.output != "" {
+		if _, err := io.WriteString(cmd.Stdout, e.output); err != nil {
+			return err
+		}
+	}
 	return e.outputErr
 }
 
@@ -21,6 +32,48 @@ func (e *MockExecutor) Output(cmd *exec.Cmd, targetDir string, action string) (s
 	return e.output, e.outputErr
 }
 
+// Run records action and returns the output set with SetOutput and the stderr set with SetStderr.
+func (e *MockExecutor) Run(cmd *exec.Cmd, targetDir string, action string) (execute.Result, error) {
+	e.history = append(e.history, action)
+	result := execute.Result{
+		Command:  cmd.String(),
+		Stdout:   e.output,
+		Stderr:   e.stderr,
+		Combined: e.output + e.stderr,
+	}
+	if e.outputErr != nil {
+		result.ExitCode = 1
+	}
+	return result, e.outputErr
+}
+
+// RunContext is like Run but returns a *execute.CancelledError, without recording action,
+// if ctx is already done.
+func (e *MockExecutor) RunContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) (execute.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return execute.Result{Command: cmd.String(), ExitCode: -1}, &execute.CancelledError{Action: action, Command: cmd.String(), Err: err}
+	}
+	return e.Run(cmd, targetDir, action)
+}
+
+// ErrorsContext is like Errors but returns a *execute.CancelledError, without recording action,
+// if ctx is already done.
+func (e *MockExecutor) ErrorsContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) error {
+	if err := ctx.Err(); err != nil {
+		return &execute.CancelledError{Action: action, Command: cmd.String(), Err: err}
+	}
+	return e.Errors(cmd, targetDir, action)
+}
+
+// OutputContext is like Output but returns a *execute.CancelledError, without recording action,
+// if ctx is already done.
+func (e *MockExecutor) OutputContext(ctx context.Context, cmd *exec.Cmd, targetDir string, action string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", &execute.CancelledError{Action: action, Command: cmd.String(), Err: err}
+	}
+	return e.Output(cmd, targetDir, action)
+}
+
 func (e *MockExecutor) CommandExists(cmd string) bool {
 	_, exists := e.cmds[cmd]
 	return exists
@@ -34,6 +87,10 @@ func (e *MockExecutor) SetOutput(o string) {
 	e.output = o
 }
 
+func (e *MockExecutor) SetStderr(s string) {
+	e.stderr = s
+}
+
 func (e *MockExecutor) SetOutputErr(err error) {
 	e.outputErr = err
 }
@@ -2,6 +2,7 @@ package mocks_test
 
 import (
 	"os/exec"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -37,3 +38,23 @@ func TestOutput_ReturnSetOutput(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "output", r)
 }
+
+func TestErrors_WritesSetOutputToCommandStdout(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput("output")
+	var stdout strings.Builder
+	err := e.Errors(&exec.Cmd{Stdout: &stdout}, "", "errors")
+	require.NoError(t, err)
+	assert.Equal(t, "output", stdout.String())
+}
+
+func TestRun_ReturnsSetOutputAndStderr(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput("out")
+	e.SetStderr("err")
+	result, err := e.Run(exec.Command("true"), ".", "running")
+	require.NoError(t, err)
+	assert.Equal(t, "out", result.Stdout)
+	assert.Equal(t, "err", result.Stderr)
+	assert.Equal(t, []string{"running"}, e.History())
+}
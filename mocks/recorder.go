@@ -0,0 +1,410 @@
+package mocks
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/vision-cli/common/execute"
+)
+
+// Exchange is one command run through a RecordingExecutor: what was written to its stdin, what
+// it wrote to stdout and stderr, and how it exited. For plugins the stdin and stdout are the
+// request and the response.
+type Exchange struct {
+	Action   string
+	Request  string
+	Response string
+	Stderr   string
+	ExitCode int // -1 if the command failed without exiting
+	Error    string
+}
+
+// exchangeFile is how an Exchange is stored in a golden file. Requests and responses that are not
+// UTF-8, such as CBOR, are stored in base64 so that they survive the round trip through JSON.
+type exchangeFile struct {
+	Action         string `json:"action"`
+	Request        string `json:"request"`
+	RequestBase64  string `json:"requestBase64,omitempty"`
+	Response       string `json:"response"`
+	ResponseBase64 string `json:"responseBase64,omitempty"`
+	Stderr         string `json:"stderr,omitempty"`
+	ExitCode       int    `json:"exitCode,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+func (x Exchange) MarshalJSON() ([]byte, error) {
+	f := exchangeFile{Action: x.Action, Stderr: x.Stderr, ExitCode: x.ExitCode, Error: x.Error}
+	f.Request, f.RequestBase64 = encodePayload(x.Request)
+	f.Response, f.ResponseBase64 = encodePayload(x.Response)
+	return json.Marshal(f)
+}
+
+func (x *Exchange) UnmarshalJSON(data []byte) error {
+	var f exchangeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	request, err := decodePayload(f.Request, f.RequestBase64)
+	if err != nil {
+		return fmt.Errorf("decoding request of %q: %w", f.Action, err)
+	}
+	response, err := decodePayload(f.Response, f.ResponseBase64)
+	if err != nil {
+		return fmt.Errorf("decoding response of %q: %w", f.Action, err)
+	}
+	*x = Exchange{Action: f.Action, Request: request, Response: response, Stderr: f.Stderr, ExitCode: f.ExitCode, Error: f.Error}
+	return nil
+}
+
+// encodePayload returns s as text, or in base64 if it is not UTF-8.
+func encodePayload(s string) (text string, b64 string) {
+	if utf8.ValidString(s) {
+		return s, ""
+	}
+	return "", base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func decodePayload(text string, b64 string) (string, error) {
+	if b64 == "" {
+		return text, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(b64)
+	return string(data), err
+}
+
+// Recording is the content of a golden file.
+type Recording struct {
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// ReadRecording reads the golden file at path.
+func ReadRecording(path string) (Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Recording{}, fmt.Errorf("reading recording: %w", err)
+	}
+	var r Recording
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Recording{}, fmt.Errorf("reading recording %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// WriteRecording writes r to the golden file at path, creating its directory if needed.
+func WriteRecording(path string, r Recording) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// RecordingExecutor runs commands with another executor and records each exchange, so that
+// plugin conversations can later be served by a ReplayExecutor without running any binaries.
+type RecordingExecutor struct {
+	executor execute.Executor
+	mu       sync.Mutex
+	recorded Recording
+}
+
+// NewRecordingExecutor returns an executor that records the commands run by executor.
+func NewRecordingExecutor(executor execute.Executor) *RecordingExecutor {
+	return &RecordingExecutor{executor: executor}
+}
+
+func (e *RecordingExecutor) Errors(cmd *exec.Cmd, targetDir string, action string) error {
+	request, err := readStdin(cmd)
+	if err != nil {
+		return err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = tee(cmd.Stdout, &stdout)
+	cmd.Stderr = tee(cmd.Stderr, &stderr)
+	err = e.executor.Errors(cmd, targetDir, action)
+	e.record(Exchange{Action: action, Request: request, Response: stdout.String(), Stderr: stderr.String()}, err)
+	return err
+}
+
+func (e *RecordingExecutor) Output(cmd *exec.Cmd, targetDir string, action string) (string, error) {
+	request, err := readStdin(cmd)
+	if err != nil {
+		return "", err
+	}
+	// a nil cmd.Stderr is left for the executor, which keeps it in any *exec.ExitError
+	var stderr bytes.Buffer
+	if cmd.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, &stderr)
+	}
+	response, err := e.executor.Output(cmd, targetDir, action)
+	var exitErr *exec.ExitError
+	if stderr.Len() == 0 && errors.As(err, &exitErr) {
+		stderr.Write(exitErr.Stderr)
+	}
+	e.record(Exchange{Action: action, Request: request, Response: response, Stderr: stderr.String()}, err)
+	return response, err
+}
+
+func (e *RecordingExecutor) Run(cmd *exec.Cmd, targetDir string, action string) (execute.Result, error) {
+	request, err := readStdin(cmd)
+	if err != nil {
+		return execute.Result{Command: cmd.String(), ExitCode: -1}, err
+	}
+	result, err := execute.RunWith(e.executor, cmd, targetDir, action)
+	e.record(Exchange{
+		Action:   action,
+		Request:  request,
+		Response: result.Stdout,
+		Stderr:   result.Stderr,
+		ExitCode: result.ExitCode,
+	}, err)
+	return result, err
+}
+
+func (e *RecordingExecutor) CommandExists(cmd string) bool {
+	return e.executor.CommandExists(cmd)
+}
+
+// Recording returns the exchanges recorded so far, in the order the commands finished.
+func (e *RecordingExecutor) Recording() Recording {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return Recording{Exchanges: append([]Exchange{}, e.recorded.Exchanges...)}
+}
+
+// Save writes the exchanges recorded so far to the golden file at path.
+func (e *RecordingExecutor) Save(path string) error {
+	return WriteRecording(path, e.Recording())
+}
+
+// record adds exchange, taking its error and, unless already set, its exit code from err.
+func (e *RecordingExecutor) record(exchange Exchange, err error) {
+	if err != nil {
+		exchange.Error = err.Error()
+		if exchange.ExitCode == 0 {
+			exchange.ExitCode = -1
+			var exitErr interface{ ExitCode() int }
+			if errors.As(err, &exitErr) {
+				exchange.ExitCode = exitErr.ExitCode()
+			}
+		}
+	}
+	e.mu.Lock()
+	e.recorded.Exchanges = append(e.recorded.Exchanges, exchange)
+	e.mu.Unlock()
+}
+
+// ReplayedError is returned by a ReplayExecutor for a command that failed when it was recorded.
+type ReplayedError struct {
+	Message string
+	Code    int // the recorded exit code, -1 if the command failed without exiting
+}
+
+func (e *ReplayedError) Error() string {
+	return e.Message
+}
+
+// ExitCode returns the recorded exit code, as *exec.ExitError does for commands that are run.
+func (e *ReplayedError) ExitCode() int {
+	return e.Code
+}
+
+// MismatchError is returned by a ReplayExecutor for a command that is not in its recording.
+type MismatchError struct {
+	Action  string
+	Request string
+	Diff    string // unified diff from the closest recorded request, empty if there is none
+}
+
+func (e *MismatchError) Error() string {
+	if e.Diff == "" {
+		return fmt.Sprintf("no recorded exchange for %q with request %s", e.Action, e.Request)
+	}
+	return fmt.Sprintf("request for %q differs from the recording:\n%s", e.Action, e.Diff)
+}
+
+// ReplayExecutor answers commands from a recording instead of running them.
+// A command is answered by the first unused exchange with the same action and request,
+// so concurrent calls may be replayed in any order.
+type ReplayExecutor struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+	used      []bool
+}
+
+// NewReplayExecutor returns an executor that replays r.
+func NewReplayExecutor(r Recording) *ReplayExecutor {
+	return &ReplayExecutor{exchanges: r.Exchanges, used: make([]bool, len(r.Exchanges))}
+}
+
+// LoadReplayExecutor returns an executor that replays the golden file at path.
+func LoadReplayExecutor(path string) (*ReplayExecutor, error) {
+	r, err := ReadRecording(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayExecutor(r), nil
+}
+
+func (e *ReplayExecutor) Errors(cmd *exec.Cmd, targetDir string, action string) error {
+	exchange, err := e.replay(cmd, action)
+	if err != nil {
+		return err
+	}
+	if err := write(cmd.Stdout, exchange.Response); err != nil {
+		return err
+	}
+	if err := write(cmd.Stderr, exchange.Stderr); err != nil {
+		return err
+	}
+	return exchange.err()
+}
+
+func (e *ReplayExecutor) Output(cmd *exec.Cmd, targetDir string, action string) (string, error) {
+	exchange, err := e.replay(cmd, action)
+	if err != nil {
+		return "", err
+	}
+	if err := write(cmd.Stderr, exchange.Stderr); err != nil {
+		return "", err
+	}
+	return exchange.Response, exchange.err()
+}
+
+func (e *ReplayExecutor) Run(cmd *exec.Cmd, targetDir string, action string) (execute.Result, error) {
+	exchange, err := e.replay(cmd, action)
+	if err != nil {
+		return execute.Result{Command: cmd.String(), ExitCode: -1}, err
+	}
+	return execute.Result{
+		Command:  cmd.String(),
+		Stdout:   exchange.Response,
+		Stderr:   exchange.Stderr,
+		Combined: exchange.Response + exchange.Stderr,
+		ExitCode: exchange.ExitCode,
+	}, exchange.err()
+}
+
+// CommandExists returns true, since replayed commands are never run.
+func (e *ReplayExecutor) CommandExists(cmd string) bool {
+	return true
+}
+
+// Unused returns an error listing the recorded exchanges that have not been replayed.
+func (e *ReplayExecutor) Unused() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var unused []string
+	for i, exchange := range e.exchanges {
+		if !e.used[i] {
+			unused = append(unused, fmt.Sprintf("%q with request %s", exchange.Action, exchange.Request))
+		}
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+	return fmt.Errorf("recorded exchanges not replayed:\n%s", strings.Join(unused, "\n"))
+}
+
+func (e *ReplayExecutor) replay(cmd *exec.Cmd, action string) (Exchange, error) {
+	request, err := readStdin(cmd)
+	if err != nil {
+		return Exchange{}, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	closest := -1
+	for i, exchange := range e.exchanges {
+		if e.used[i] || exchange.Action != action {
+			continue
+		}
+		if exchange.Request == request {
+			e.used[i] = true
+			return exchange, nil
+		}
+		if closest < 0 {
+			closest = i
+		}
+	}
+	mismatch := &MismatchError{Action: action, Request: request}
+	if closest >= 0 {
+		mismatch.Diff = diff(e.exchanges[closest].Request, request)
+	}
+	return Exchange{}, mismatch
+}
+
+// err returns the recorded error as a *ReplayedError. Recordings made before exit codes were
+// kept report -1.
+func (x Exchange) err() error {
+	if x.Error == "" {
+		return nil
+	}
+	code := x.ExitCode
+	if code == 0 {
+		code = -1
+	}
+	return &ReplayedError{Message: x.Error, Code: code}
+}
+
+// tee returns a writer that writes to both w, which may be nil, and buf.
+func tee(w io.Writer, buf *bytes.Buffer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(w, buf)
+}
+
+// write writes s to w, if w is set.
+func write(w io.Writer, s string) error {
+	if w == nil || s == "" {
+		return nil
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+// readStdin returns what cmd would read from stdin, leaving cmd able to read it again.
+func readStdin(cmd *exec.Cmd) (string, error) {
+	if cmd.Stdin == nil {
+		return "", nil
+	}
+	data, err := io.ReadAll(cmd.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("reading stdin of %q: %w", cmd.String(), err)
+	}
+	cmd.Stdin = bytes.NewReader(data)
+	return string(data), nil
+}
+
+// diff returns a unified diff of two requests, indenting them first if they are JSON.
+func diff(recorded string, actual string) string {
+	out, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(indentJSON(recorded)),
+		B:        difflib.SplitLines(indentJSON(actual)),
+		FromFile: "recorded",
+		ToFile:   "actual",
+		Context:  2,
+	})
+	return out
+}
+
+func indentJSON(s string) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return out.String() + "\n"
+}
@@ -0,0 +1,181 @@
+package mocks_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/comms"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/mocks"
+	"github.com/vision-cli/common/plugins"
+)
+
+type echoResponse struct {
+	Msg string
+}
+
+// echoPlugin writes a plugin that answers with its request.
+func echoPlugin(t *testing.T) plugins.Plugin {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vision-plugin-echo-v1")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\nprintf '{\"Msg\":\"%s\"}' \"$(cat | tr -d '\"')\"\n"), 0o755))
+	return plugins.Plugin{Name: "vision-plugin-echo-v1", PluginPath: path}
+}
+
+func runRequest(args ...string) *api_v1.PluginRequest {
+	return &api_v1.PluginRequest{Command: api_v1.CommandRun, Args: args}
+}
+
+func TestRecordingExecutor_RecordsAndReplaysCalls(t *testing.T) {
+	plugin := echoPlugin(t)
+	golden := filepath.Join(t.TempDir(), "testdata", "echo.json")
+
+	recorder := mocks.NewRecordingExecutor(execute.NewOsExecutor())
+	recorded, err := comms.Call[echoResponse](plugin, runRequest("a"), recorder)
+	require.NoError(t, err)
+	require.NoError(t, recorder.Save(golden))
+	require.NoError(t, os.Remove(plugin.PluginPath))
+
+	replayer, err := mocks.LoadReplayExecutor(golden)
+	require.NoError(t, err)
+	replayed, err := comms.Call[echoResponse](plugin, runRequest("a"), replayer)
+	require.NoError(t, err)
+	assert.Equal(t, recorded, replayed)
+	assert.NoError(t, replayer.Unused())
+}
+
+func TestRecordingExecutor_RecordsErrors(t *testing.T) {
+	recorder := mocks.NewRecordingExecutor(execute.NewOsExecutor())
+	cmd := exec.Command("sh", "-c", "cat; exit 3")
+	cmd.Stdin = strings.NewReader("in")
+
+	out, err := recorder.Output(cmd, ".", "failing")
+	require.Error(t, err)
+	assert.Empty(t, out)
+	assert.Equal(t, []mocks.Exchange{{Action: "failing", Request: "in", ExitCode: 3, Error: err.Error()}}, recorder.Recording().Exchanges)
+
+	replayer := mocks.NewReplayExecutor(recorder.Recording())
+	replayCmd := exec.Command("sh")
+	replayCmd.Stdin = strings.NewReader("in")
+	out, replayErr := replayer.Output(replayCmd, ".", "failing")
+	assert.Empty(t, out)
+	assert.EqualError(t, replayErr, err.Error())
+}
+
+func TestReplayExecutor_WithDifferentRequest_ReturnsDiff(t *testing.T) {
+	plugin := echoPlugin(t)
+	recorder := mocks.NewRecordingExecutor(execute.NewOsExecutor())
+	_, err := comms.Call[echoResponse](plugin, runRequest("a"), recorder)
+	require.NoError(t, err)
+
+	replayer := mocks.NewReplayExecutor(recorder.Recording())
+	_, err = comms.Call[echoResponse](plugin, runRequest("b"), replayer)
+
+	var mismatch *mocks.MismatchError
+	require.True(t, errors.As(err, &mismatch))
+	assert.Contains(t, mismatch.Diff, "--- recorded")
+	assert.Contains(t, mismatch.Diff, `-    "a"`)
+	assert.Contains(t, mismatch.Diff, `+    "b"`)
+	assert.Error(t, replayer.Unused())
+}
+
+func TestReplayExecutor_WithUnknownAction_ReturnsMismatch(t *testing.T) {
+	replayer := mocks.NewReplayExecutor(mocks.Recording{})
+	_, err := replayer.Output(exec.Command("true"), ".", "unknown")
+
+	var mismatch *mocks.MismatchError
+	require.True(t, errors.As(err, &mismatch))
+	assert.Empty(t, mismatch.Diff)
+}
+
+func TestReplayExecutor_ReplaysCallsInAnyOrder(t *testing.T) {
+	plugin := echoPlugin(t)
+	recorder := mocks.NewRecordingExecutor(execute.NewOsExecutor())
+	for _, arg := range []string{"a", "b"} {
+		_, err := comms.Call[echoResponse](plugin, runRequest(arg), recorder)
+		require.NoError(t, err)
+	}
+
+	replayer := mocks.NewReplayExecutor(recorder.Recording())
+	for _, arg := range []string{"b", "a"} {
+		result, err := comms.Call[echoResponse](plugin, runRequest(arg), replayer)
+		require.NoError(t, err)
+		assert.Contains(t, result.Msg, "Args:["+arg+"]")
+	}
+	assert.NoError(t, replayer.Unused())
+}
+
+func TestReplayExecutor_Errors_WritesResponseToStdout(t *testing.T) {
+	replayer := mocks.NewReplayExecutor(mocks.Recording{Exchanges: []mocks.Exchange{{Action: "streaming", Response: "line\n"}}})
+	var out strings.Builder
+	cmd := exec.Command("true")
+	cmd.Stdout = &out
+
+	require.NoError(t, replayer.Errors(cmd, ".", "streaming"))
+	assert.Equal(t, "line\n", out.String())
+}
+
+func TestRecordingExecutor_RecordsAndReplaysRunResults(t *testing.T) {
+	recorder := mocks.NewRecordingExecutor(execute.NewOsExecutor())
+	recorded, err := recorder.Run(exec.Command("sh", "-c", "echo out; echo err >&2; exit 2"), ".", "running")
+	require.Error(t, err)
+
+	replayer := mocks.NewReplayExecutor(recorder.Recording())
+	replayed, replayErr := replayer.Run(exec.Command("sh"), ".", "running")
+	assert.EqualError(t, replayErr, err.Error())
+	assert.Equal(t, recorded.Stdout, replayed.Stdout)
+	assert.Equal(t, recorded.Stderr, replayed.Stderr)
+	assert.Equal(t, 2, replayed.ExitCode)
+}
+
+func TestReplayExecutor_ReplaysPluginFailuresLikeTheRecording(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vision-plugin-legacy-v1")
+	script := "#!/bin/sh\ncat > /dev/null\n" +
+		"[ \"$" + comms.HandshakeEnvVar + "\" = 1 ] && { echo unknown command >&2; exit 2; }\n" +
+		"echo config missing >&2\nexit 3\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	plugin := plugins.Plugin{Name: "vision-plugin-legacy-v1", PluginPath: path}
+
+	call := func(e execute.Executor) (*comms.Protocol, *comms.PluginError) {
+		protocol, err := comms.Handshake(context.Background(), plugin, e)
+		require.NoError(t, err)
+		_, err = comms.Call[echoResponse](plugin, runRequest("a"), e, comms.WithProtocol(protocol))
+		var pluginErr *comms.PluginError
+		require.ErrorAs(t, err, &pluginErr)
+		return protocol, pluginErr
+	}
+	recorder := mocks.NewRecordingExecutor(execute.NewOsExecutor())
+	recordedProtocol, recordedErr := call(recorder)
+	assert.True(t, recordedProtocol.Legacy)
+	assert.Equal(t, 3, recordedErr.ExitCode)
+	assert.Equal(t, "config missing\n", recordedErr.Stderr)
+
+	replayer := mocks.NewReplayExecutor(recorder.Recording())
+	replayedProtocol, replayedErr := call(replayer)
+	assert.Equal(t, recordedProtocol.Legacy, replayedProtocol.Legacy)
+	assert.Equal(t, recordedProtocol.Version, replayedProtocol.Version)
+	assert.Equal(t, recordedErr.ExitCode, replayedErr.ExitCode)
+	assert.Equal(t, recordedErr.Stderr, replayedErr.Stderr)
+	assert.Equal(t, recordedErr.Error(), replayedErr.Error())
+	assert.NoError(t, replayer.Unused())
+}
+
+func TestWriteRecording_KeepsBinaryPayloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "binary.json")
+	recording := mocks.Recording{Exchanges: []mocks.Exchange{
+		{Action: "calling plugin", Request: "\xa4dArgs\x80", Response: `{"Msg":"text"}`},
+	}}
+	require.NoError(t, mocks.WriteRecording(path, recording))
+
+	read, err := mocks.ReadRecording(path)
+	require.NoError(t, err)
+	assert.Equal(t, recording, read)
+}
@@ -1,6 +1,7 @@
 package module
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"path/filepath"
@@ -33,12 +34,33 @@ func Init(targetDir string, moduleName string, executor execute.Executor) error
 	return executor.Errors(init, targetDir, "initialising module")
 }
 
-// Tidy tidies module dependencies.
+// Tidy tidies module dependencies. If go mod tidy fails and executor is an execute.Runner, the
+// returned error includes what it wrote to stderr.
 func Tidy(moduleDir string, executor execute.Executor) error {
 	tidy := exec.Command("go", "mod", "tidy")
+	if runner, ok := executor.(execute.Runner); ok {
+		return withStderr(runner.Run(tidy, moduleDir, "finding required module dependencies"))
+	}
 	return executor.Errors(tidy, moduleDir, "finding required module dependencies")
 }
 
+// TidyContext is like Tidy but stops go mod tidy when ctx is done.
+func TidyContext(ctx context.Context, moduleDir string, executor execute.ExecutorContext) error {
+	tidy := exec.Command("go", "mod", "tidy")
+	return withStderr(executor.RunContext(ctx, tidy, moduleDir, "finding required module dependencies"))
+}
+
+// withStderr adds the stderr of a failed command to its error.
+func withStderr(result execute.Result, err error) error {
+	if err == nil {
+		return nil
+	}
+	if stderr := strings.TrimSpace(result.Stderr); stderr != "" {
+		return fmt.Errorf("%w\n%s", err, stderr)
+	}
+	return err
+}
+
 // Name returns the module name found in moduleDir/go.mod.
 func Name(moduleDir string) (string, error) {
 	modPath := filepath.Join(moduleDir, modFile)
@@ -1,10 +1,13 @@
 package module_test
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vision-cli/common/execute"
 	"github.com/vision-cli/common/file"
 	"github.com/vision-cli/common/mocks"
 	"github.com/vision-cli/common/module"
@@ -35,6 +38,32 @@ func TestTidy_RunsGoModTidy(t *testing.T) {
 	assert.Equal(t, "finding required module dependencies", e.History()[0])
 }
 
+func TestTidy_WhenGoModTidyFails_ReturnsStderr(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutputErr(errors.New("finding required module dependencies: exit status 1"))
+	e.SetStderr("go: example.com/missing@v1.0.0: reading example.com/missing: 404 Not Found\n")
+	err := module.Tidy("targetdir", &e)
+	assert.EqualError(t, err, "finding required module dependencies: exit status 1\n"+
+		"go: example.com/missing@v1.0.0: reading example.com/missing: 404 Not Found")
+}
+
+func TestTidy_WhenExecutorIsNotRunner_UsesErrors(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutputErr(errors.New("finding required module dependencies: exit status 1"))
+	err := module.Tidy("targetdir", struct{ execute.Executor }{&e})
+	assert.EqualError(t, err, "finding required module dependencies: exit status 1")
+	assert.Equal(t, []string{"finding required module dependencies"}, e.History())
+}
+
+func TestTidyContext_WhenCancelled_DoesNotRunGoModTidy(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := module.TidyContext(ctx, "targetdir", &e)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, e.History())
+}
+
 func TestName_ReturnsModName(t *testing.T) {
 	old := file.ToLines
 	defer func() { file.ToLines = old }()
@@ -0,0 +1,116 @@
+package plugins
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/file"
+)
+
+const (
+	latestVersion = "latest"
+)
+
+var majorVersionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// Install runs go install for the plugin package at modulePath and version, or the latest version
+// if version is empty, into dir, or $GOBIN or $GOPATH/bin if dir is empty.
+// The package must build a binary following the vision-plugin-<name>-<version> naming convention.
+func Install(modulePath string, version string, dir string, executor execute.Executor) (Plugin, error) {
+	name := binaryName(modulePath)
+	if !fileIsVisionPlugin(name) {
+		return Plugin{}, fmt.Errorf("%s does not build a vision plugin: binary %s is not named %s-%s-<name>-<version>",
+			modulePath, name, visionFirstWord, visionSecondWord)
+	}
+	if version == "" {
+		version = latestVersion
+	}
+	dir, err := installDir(dir, executor)
+	if err != nil {
+		return Plugin{}, err
+	}
+
+	install := exec.Command("go", "install", fmt.Sprintf("%s@%s", modulePath, version))
+	install.Env = append(install.Environ(), goBinEnvVar+"="+dir)
+	if err := executor.Errors(install, ".", fmt.Sprintf("installing plugin %s@%s", modulePath, version)); err != nil {
+		return Plugin{}, err
+	}
+
+	plugin := Plugin{Name: name, PluginPath: filepath.Join(dir, name), SearchPath: dir}
+	if !file.Exists(plugin.PluginPath) {
+		return Plugin{}, fmt.Errorf("installing plugin %s: %s was not created", modulePath, plugin.PluginPath)
+	}
+	manifest, ok, err := LoadManifest(plugin.PluginPath)
+	if err != nil {
+		return Plugin{}, err
+	}
+	if ok {
+		manifest.apply(&plugin)
+	}
+	return plugin, nil
+}
+
+// Upgrade installs the latest version of the plugin package at modulePath into dir.
+func Upgrade(modulePath string, dir string, executor execute.Executor) (Plugin, error) {
+	return Install(modulePath, latestVersion, dir, executor)
+}
+
+// InstallLock installs every plugin in lock at its locked module and version into dir.
+func InstallLock(lock Lock, dir string, executor execute.Executor) ([]Plugin, error) {
+	var installed []Plugin
+	for _, locked := range lock.Plugins {
+		if locked.Module == "" {
+			return installed, fmt.Errorf("cannot install plugin %s: lock has no module path", locked.Name)
+		}
+		plugin, err := Install(locked.Module, locked.Version, dir, executor)
+		if err != nil {
+			return installed, err
+		}
+		installed = append(installed, plugin)
+	}
+	return installed, nil
+}
+
+// Remove deletes the named plugin and any manifest beside it from dir, or $GOBIN or $GOPATH/bin
+// if dir is empty.
+func Remove(name string, dir string, executor execute.Executor) error {
+	if !fileIsVisionPlugin(name) {
+		return fmt.Errorf("%s is not a vision plugin name", name)
+	}
+	dir, err := installDir(dir, executor)
+	if err != nil {
+		return err
+	}
+	if !file.Exists(filepath.Join(dir, name)) {
+		return fmt.Errorf("plugin %s is not installed in %s", name, dir)
+	}
+	named := []string{name}
+	for _, ext := range manifestExtensions {
+		named = append(named, name+ext)
+	}
+	if err := file.RemoveNamed(dir, named...); err != nil {
+		return fmt.Errorf("removing plugin %s: %w", name, err)
+	}
+	return nil
+}
+
+// binaryName returns the name go install gives the binary built from packagePath,
+// which is its last element unless that is a major version suffix.
+func binaryName(packagePath string) string {
+	name := path.Base(packagePath)
+	if majorVersionSuffix.MatchString(name) {
+		name = path.Base(path.Dir(packagePath))
+	}
+	return name
+}
+
+func installDir(dir string, executor execute.Executor) (string, error) {
+	if dir == "" {
+		return goBinPath(executor)
+	}
+	return filepath.Abs(dir)
+}
@@ -0,0 +1,105 @@
+package plugins_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vision-cli/common/file"
+	"github.com/vision-cli/common/mocks"
+	"github.com/vision-cli/common/plugins"
+)
+
+func TestInstall_RunsGoInstallIntoDir(t *testing.T) {
+	dir := makePluginDir(t, "vision-plugin-service-v1")
+	e := mocks.NewMockExecutor()
+	p, err := plugins.Install("github.com/org/vision-plugin-service-v1", "v1.2.0", dir, &e)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"installing plugin github.com/org/vision-plugin-service-v1@v1.2.0"}, e.History())
+	assert.Equal(t, "vision-plugin-service-v1", p.Name)
+	assert.Equal(t, filepath.Join(dir, "vision-plugin-service-v1"), p.PluginPath)
+}
+
+func TestInstall_WhenPathHasMajorVersionSuffix_UsesParentElementAsName(t *testing.T) {
+	dir := makePluginDir(t, "vision-plugin-service-v2")
+	e := mocks.NewMockExecutor()
+	p, err := plugins.Install("github.com/org/vision-plugin-service-v2/v2", "", dir, &e)
+	require.NoError(t, err)
+	assert.Equal(t, "vision-plugin-service-v2", p.Name)
+	assert.Equal(t, []string{"installing plugin github.com/org/vision-plugin-service-v2/v2@latest"}, e.History())
+}
+
+func TestInstall_WhenNameIsNotAVisionPlugin_ReturnsErrorWithoutInstalling(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	_, err := plugins.Install("github.com/org/some-tool", "", t.TempDir(), &e)
+	require.Error(t, err)
+	assert.Empty(t, e.History())
+}
+
+func TestInstall_WhenBinaryIsNotCreated_ReturnsError(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	_, err := plugins.Install("github.com/org/vision-plugin-service-v1", "", t.TempDir(), &e)
+	require.Error(t, err)
+}
+
+func TestInstall_WhenGoInstallFails_ReturnsError(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutputErr(errors.New("module not found"))
+	_, err := plugins.Install("github.com/org/vision-plugin-service-v1", "", t.TempDir(), &e)
+	require.Error(t, err)
+}
+
+func TestUpgrade_InstallsLatest(t *testing.T) {
+	dir := makePluginDir(t, "vision-plugin-service-v1")
+	e := mocks.NewMockExecutor()
+	_, err := plugins.Upgrade("github.com/org/vision-plugin-service-v1", dir, &e)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"installing plugin github.com/org/vision-plugin-service-v1@latest"}, e.History())
+}
+
+func TestInstallLock_InstallsEveryLockedPlugin(t *testing.T) {
+	dir := makePluginDir(t, "vision-plugin-a-v1", "vision-plugin-b-v1")
+	lock := plugins.Lock{Plugins: []plugins.LockedPlugin{
+		{Name: "vision-plugin-a-v1", Module: "github.com/org/vision-plugin-a-v1", Version: "v1.0.0"},
+		{Name: "vision-plugin-b-v1", Module: "github.com/org/vision-plugin-b-v1", Version: "v1.3.0"},
+	}}
+	e := mocks.NewMockExecutor()
+	installed, err := plugins.InstallLock(lock, dir, &e)
+	require.NoError(t, err)
+	assert.Len(t, installed, 2)
+	assert.Equal(t, []string{
+		"installing plugin github.com/org/vision-plugin-a-v1@v1.0.0",
+		"installing plugin github.com/org/vision-plugin-b-v1@v1.3.0",
+	}, e.History())
+}
+
+func TestRemove_DeletesPluginAndManifest(t *testing.T) {
+	dir := makePluginDir(t, "vision-plugin-a-v1", "vision-plugin-a-v1.yaml", "vision-plugin-b-v1")
+	e := mocks.NewMockExecutor()
+	require.NoError(t, plugins.Remove("vision-plugin-a-v1", dir, &e))
+	_, err := os.Stat(filepath.Join(dir, "vision-plugin-a-v1"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = os.Stat(filepath.Join(dir, "vision-plugin-a-v1.yaml"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = os.Stat(filepath.Join(dir, "vision-plugin-b-v1"))
+	assert.NoError(t, err)
+}
+
+func TestRemove_WhenNotInstalled_ReturnsError(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	require.Error(t, plugins.Remove("vision-plugin-a-v1", t.TempDir(), &e))
+}
+
+func TestRemove_WhenDirIsEmpty_UsesGoBin(t *testing.T) {
+	goBin := makePluginDir(t, "vision-plugin-a-v1")
+	old := file.Osgetenv
+	defer func() { file.Osgetenv = old }()
+	file.Osgetenv = mockEnv(map[string]string{"GOBIN": goBin})
+
+	e := mocks.NewMockExecutor()
+	require.NoError(t, plugins.Remove("vision-plugin-a-v1", "", &e))
+}
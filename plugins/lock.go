@@ -0,0 +1,151 @@
+package plugins
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/vision-cli/common/file"
+)
+
+const (
+	// LockFile is the name of the plugin lock file in a project directory.
+	LockFile = "vision-plugins.lock"
+)
+
+// Lock pins the external plugins a project expects.
+type Lock struct {
+	Plugins []LockedPlugin `yaml:"plugins"`
+}
+
+// LockedPlugin is a single pinned plugin.
+type LockedPlugin struct {
+	Name     string `yaml:"name"`
+	Version  string `yaml:"version,omitempty"`
+	Module   string `yaml:"module,omitempty"`
+	Checksum string `yaml:"sha256"`
+}
+
+// Drift is the difference between a lock and the installed plugins.
+type Drift struct {
+	Missing []LockedPlugin  // locked but not installed
+	Extra   []LockedPlugin  // installed but not locked
+	Drifted []DriftedPlugin // installed with a different version or checksum
+}
+
+// DriftedPlugin pairs a locked plugin with what is installed under its name.
+type DriftedPlugin struct {
+	Locked    LockedPlugin
+	Installed LockedPlugin
+}
+
+// Clean returns true if the installed plugins match the lock exactly.
+func (d Drift) Clean() bool {
+	return len(d.Missing) == 0 && len(d.Extra) == 0 && len(d.Drifted) == 0
+}
+
+// NewLock pins the external plugins in plugins, sorted by name.
+// The module path and version are read from the build information of Go binaries; the
+// manifest version is used for plugins without it.
+func NewLock(plugins []Plugin) (Lock, error) {
+	var lock Lock
+	for _, p := range plugins {
+		if p.InternalCommand != nil {
+			continue
+		}
+		locked, err := lockPlugin(p)
+		if err != nil {
+			return Lock{}, err
+		}
+		lock.Plugins = append(lock.Plugins, locked)
+	}
+	sort.Slice(lock.Plugins, func(i, j int) bool {
+		return lock.Plugins[i].Name < lock.Plugins[j].Name
+	})
+	return lock, nil
+}
+
+func lockPlugin(p Plugin) (LockedPlugin, error) {
+	sum, err := Checksum(p.PluginPath)
+	if err != nil {
+		return LockedPlugin{}, fmt.Errorf("locking plugin %s: %w", p.Name, err)
+	}
+	locked := LockedPlugin{Name: p.Name, Version: p.Version, Checksum: sum}
+	if info, err := buildinfo.ReadFile(p.PluginPath); err == nil {
+		locked.Module = info.Main.Path
+		if info.Main.Version != "" && info.Main.Version != "(devel)" {
+			locked.Version = info.Main.Version
+		}
+	}
+	return locked, nil
+}
+
+// ReadLock reads the lock file in projectDir.
+func ReadLock(projectDir string) (Lock, error) {
+	path := filepath.Join(projectDir, LockFile)
+	data, err := file.ReadFile(path)
+	if err != nil {
+		return Lock{}, fmt.Errorf("reading plugin lock: %w", err)
+	}
+	var lock Lock
+	if err := yaml.UnmarshalStrict(data, &lock); err != nil {
+		return Lock{}, fmt.Errorf("parsing plugin lock %s: %w", path, err)
+	}
+	return lock, nil
+}
+
+// WriteLock writes lock to the lock file in projectDir.
+func WriteLock(projectDir string, lock Lock) error {
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("marshalling plugin lock: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, LockFile), data, 0o644); err != nil { //nolint:gomnd //permission mask has inherent meaning
+		return fmt.Errorf("writing plugin lock: %w", err)
+	}
+	return nil
+}
+
+// Checksums returns the locked checksums, for use in a Verifier.
+func (l Lock) Checksums() Checksums {
+	checksums := Checksums{}
+	for _, p := range l.Plugins {
+		checksums[p.Name] = p.Checksum
+	}
+	return checksums
+}
+
+// Compare reports how the external plugins in installed differ from the lock.
+func (l Lock) Compare(installed []Plugin) (Drift, error) {
+	current, err := NewLock(installed)
+	if err != nil {
+		return Drift{}, err
+	}
+	byName := map[string]LockedPlugin{}
+	for _, p := range current.Plugins {
+		byName[p.Name] = p
+	}
+
+	var drift Drift
+	locked := map[string]bool{}
+	for _, want := range l.Plugins {
+		locked[want.Name] = true
+		have, ok := byName[want.Name]
+		switch {
+		case !ok:
+			drift.Missing = append(drift.Missing, want)
+		case have.Checksum != want.Checksum || (want.Version != "" && have.Version != want.Version):
+			drift.Drifted = append(drift.Drifted, DriftedPlugin{Locked: want, Installed: have})
+		}
+	}
+	for _, have := range current.Plugins {
+		if !locked[have.Name] {
+			drift.Extra = append(drift.Extra, have)
+		}
+	}
+	return drift, nil
+}
@@ -0,0 +1,80 @@
+package plugins_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vision-cli/common/plugins"
+)
+
+func TestNewLock_PinsExternalPluginsSortedByName(t *testing.T) {
+	dir := makePluginDir(t, "vision-plugin-b-v1", "vision-plugin-a-v1")
+	installed := []plugins.Plugin{
+		{Name: "vision-plugin-b-v1", PluginPath: filepath.Join(dir, "vision-plugin-b-v1"), Version: "v1.1.0"},
+		{Name: "vision-plugin-a-v1", PluginPath: filepath.Join(dir, "vision-plugin-a-v1")},
+		{Name: "vision-plugin-internal-v1", InternalCommand: dummyPluginHandler},
+	}
+	lock, err := plugins.NewLock(installed)
+	require.NoError(t, err)
+	require.Len(t, lock.Plugins, 2)
+	assert.Equal(t, "vision-plugin-a-v1", lock.Plugins[0].Name)
+	assert.Equal(t, "vision-plugin-b-v1", lock.Plugins[1].Name)
+	assert.Equal(t, "v1.1.0", lock.Plugins[1].Version)
+	assert.Len(t, lock.Plugins[0].Checksum, 64)
+}
+
+func TestNewLock_ForGoBinary_RecordsModulePath(t *testing.T) {
+	lock, err := plugins.NewLock([]plugins.Plugin{{Name: "vision-plugin-test-v1", PluginPath: os.Args[0]}})
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/vision-cli/common", lock.Plugins[0].Module)
+}
+
+func TestWriteLock_ThenReadLock_RoundTrips(t *testing.T) {
+	dir := t.TempDir()
+	lock := plugins.Lock{Plugins: []plugins.LockedPlugin{
+		{Name: "vision-plugin-a-v1", Version: "v1.0.0", Module: "github.com/org/vision-plugin-a-v1", Checksum: otherChecksum},
+	}}
+	require.NoError(t, plugins.WriteLock(dir, lock))
+	read, err := plugins.ReadLock(dir)
+	require.NoError(t, err)
+	assert.Equal(t, lock, read)
+	assert.Equal(t, plugins.Checksums{"vision-plugin-a-v1": otherChecksum}, read.Checksums())
+}
+
+func TestReadLock_WhenMissing_ReturnsError(t *testing.T) {
+	_, err := plugins.ReadLock(t.TempDir())
+	require.Error(t, err)
+}
+
+func TestCompare_ReportsMissingExtraAndDriftedPlugins(t *testing.T) {
+	dir := makePluginDir(t, "vision-plugin-same-v1", "vision-plugin-changed-v1", "vision-plugin-new-v1")
+	installed := []plugins.Plugin{
+		{Name: "vision-plugin-same-v1", PluginPath: filepath.Join(dir, "vision-plugin-same-v1")},
+		{Name: "vision-plugin-changed-v1", PluginPath: filepath.Join(dir, "vision-plugin-changed-v1")},
+		{Name: "vision-plugin-new-v1", PluginPath: filepath.Join(dir, "vision-plugin-new-v1")},
+	}
+	current, err := plugins.NewLock(installed[:1])
+	require.NoError(t, err)
+	lock := plugins.Lock{Plugins: []plugins.LockedPlugin{
+		current.Plugins[0],
+		{Name: "vision-plugin-changed-v1", Checksum: otherChecksum},
+		{Name: "vision-plugin-gone-v1", Checksum: otherChecksum},
+	}}
+
+	drift, err := lock.Compare(installed)
+	require.NoError(t, err)
+	assert.False(t, drift.Clean())
+	require.Len(t, drift.Missing, 1)
+	assert.Equal(t, "vision-plugin-gone-v1", drift.Missing[0].Name)
+	require.Len(t, drift.Extra, 1)
+	assert.Equal(t, "vision-plugin-new-v1", drift.Extra[0].Name)
+	require.Len(t, drift.Drifted, 1)
+	assert.Equal(t, "vision-plugin-changed-v1", drift.Drifted[0].Locked.Name)
+
+	drift, err = current.Compare(installed[:1])
+	require.NoError(t, err)
+	assert.True(t, drift.Clean())
+}
@@ -0,0 +1,170 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	api_v1 "github.com/vision-cli/api/v1"
+	"gopkg.in/yaml.v2"
+
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/file"
+	"github.com/vision-cli/common/marshal"
+)
+
+const (
+	// DescribeEnvVar is set to "1" in the environment of a plugin run by Describe.
+	DescribeEnvVar = "VISION_PLUGIN_DESCRIBE"
+)
+
+// manifestExtensions are the sidecar manifest extensions, in the order they are looked for.
+var manifestExtensions = []string{".yaml", ".yml", ".json"}
+
+// Manifest describes a plugin. It is read from a sidecar file next to the plugin binary with the
+// same name plus a .yaml, .yml or .json extension, or from the output of Describe.
+type Manifest struct {
+	Version        string   `yaml:"version" json:"version"`
+	Description    string   `yaml:"description" json:"description"`
+	Author         string   `yaml:"author" json:"author"`
+	Commands       []string `yaml:"commands" json:"commands"`
+	MinHostVersion string   `yaml:"minHostVersion" json:"minHostVersion"`
+}
+
+// LoadManifest reads the sidecar manifest of the plugin binary at pluginPath.
+// It returns false if there is no manifest. Manifests with unknown fields are rejected.
+func LoadManifest(pluginPath string) (Manifest, bool, error) {
+	for _, ext := range manifestExtensions {
+		manifestPath := pluginPath + ext
+		data, err := file.ReadFile(manifestPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Manifest{}, false, fmt.Errorf("reading plugin manifest %s: %w", manifestPath, err)
+		}
+		var m Manifest
+		if ext == ".json" {
+			m, err = marshal.Unmarshal[Manifest](string(data))
+		} else {
+			err = yaml.UnmarshalStrict(data, &m)
+		}
+		if err != nil {
+			return Manifest{}, false, fmt.Errorf("parsing plugin manifest %s: %w", manifestPath, err)
+		}
+		return m, true, nil
+	}
+	return Manifest{}, false, nil
+}
+
+// Describe runs plugin with DescribeEnvVar set and returns a copy of it with the fields of the JSON
+// manifest the plugin writes to stdout. Internal plugins are returned unchanged. Plugins that answer
+// with anything other than a manifest, such as an error response, are returned unchanged with an
+// error.
+func Describe(plugin Plugin, executor execute.Executor) (Plugin, error) {
+	if plugin.InternalCommand != nil {
+		return plugin, nil
+	}
+	cmd := exec.Command(plugin.PluginPath)
+	cmd.Env = append(cmd.Environ(), DescribeEnvVar+"=1")
+	cmd.Stdin = strings.NewReader("")
+	output, err := executor.Output(cmd, ".", "describing plugin "+plugin.Name)
+	if err != nil {
+		return plugin, err
+	}
+	m, err := marshal.Unmarshal[Manifest](output)
+	if err != nil {
+		if response, respErr := marshal.Unmarshal[api_v1.PluginResponse](output); respErr == nil && response.Error != "" {
+			return plugin, fmt.Errorf("describing plugin %s: %s", plugin.Name, response.Error)
+		}
+		return plugin, fmt.Errorf("parsing description of plugin %s: %w", plugin.Name, err)
+	}
+	m.apply(&plugin)
+	return plugin, nil
+}
+
+func (m Manifest) apply(p *Plugin) {
+	p.Version = m.Version
+	p.Description = m.Description
+	p.Author = m.Author
+	p.Commands = m.Commands
+	p.MinHostVersion = m.MinHostVersion
+}
+
+// SupportsCommand returns true if the plugin's manifest lists command.
+func (p Plugin) SupportsCommand(command string) bool {
+	for _, c := range p.Commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// CompatibleWith returns true if hostVersion is at least the plugin's minimum host version.
+// Plugins without a minimum host version are compatible with every host.
+func (p Plugin) CompatibleWith(hostVersion string) bool {
+	return p.MinHostVersion == "" || CompareVersions(hostVersion, p.MinHostVersion) >= 0
+}
+
+// Filter returns the plugins for which keep returns true.
+func Filter(plugins []Plugin, keep func(Plugin) bool) []Plugin {
+	var kept []Plugin
+	for _, p := range plugins {
+		if keep(p) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// CompareVersions compares semantic versions such as v1.2.3, returning -1, 0 or 1.
+// The "v" prefix and missing minor or patch numbers are optional. A pre-release version
+// sorts before its release; pre-release and build suffixes are otherwise not compared.
+func CompareVersions(a string, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+	for i := 0; i < len(aCore) || i < len(bCore); i++ {
+		var x, y int
+		if i < len(aCore) {
+			x = aCore[i]
+		}
+		if i < len(bCore) {
+			y = bCore[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aPre && !bPre:
+		return -1
+	case !aPre && bPre:
+		return 1
+	}
+	return 0
+}
+
+func splitVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+	prerelease := false
+	if i := strings.Index(v, "-"); i >= 0 {
+		v = v[:i]
+		prerelease = true
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(s)
+		parts = append(parts, n)
+	}
+	return parts, prerelease
+}
@@ -0,0 +1,213 @@
+package plugins_test
+
+import (
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vision-cli/common/file"
+	"github.com/vision-cli/common/mocks"
+	"github.com/vision-cli/common/plugins"
+)
+
+const yamlManifest = `version: v1.2.0
+description: Generates services
+author: Vision
+commands:
+  - create
+  - delete
+minHostVersion: v0.5.0
+`
+
+func mockReadFile(files map[string]string) func(string) ([]byte, error) {
+	return func(name string) ([]byte, error) {
+		if content, ok := files[name]; ok {
+			return []byte(content), nil
+		}
+		return nil, fs.ErrNotExist
+	}
+}
+
+func TestLoadManifest_WhenYamlSidecarExists_ReturnsManifest(t *testing.T) {
+	old := file.Osreadfile
+	defer func() { file.Osreadfile = old }()
+	file.Osreadfile = mockReadFile(map[string]string{"/bin/vision-plugin-a-v1.yaml": yamlManifest})
+
+	m, found, err := plugins.LoadManifest("/bin/vision-plugin-a-v1")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, plugins.Manifest{
+		Version:        "v1.2.0",
+		Description:    "Generates services",
+		Author:         "Vision",
+		Commands:       []string{"create", "delete"},
+		MinHostVersion: "v0.5.0",
+	}, m)
+}
+
+func TestLoadManifest_WhenJsonSidecarExists_ReturnsManifest(t *testing.T) {
+	old := file.Osreadfile
+	defer func() { file.Osreadfile = old }()
+	file.Osreadfile = mockReadFile(map[string]string{"/bin/vision-plugin-a-v1.json": `{"version":"v2.0.0","commands":["run"]}`})
+
+	m, found, err := plugins.LoadManifest("/bin/vision-plugin-a-v1")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "v2.0.0", m.Version)
+	assert.Equal(t, []string{"run"}, m.Commands)
+}
+
+func TestLoadManifest_WhenNoSidecar_ReturnsNotFound(t *testing.T) {
+	old := file.Osreadfile
+	defer func() { file.Osreadfile = old }()
+	file.Osreadfile = mockReadFile(nil)
+
+	_, found, err := plugins.LoadManifest("/bin/vision-plugin-a-v1")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestLoadManifest_WhenSidecarIsInvalid_ReturnsError(t *testing.T) {
+	old := file.Osreadfile
+	defer func() { file.Osreadfile = old }()
+	file.Osreadfile = mockReadFile(map[string]string{"/bin/vision-plugin-a-v1.yaml": "unknown: field\n"})
+
+	_, _, err := plugins.LoadManifest("/bin/vision-plugin-a-v1")
+	require.Error(t, err)
+}
+
+func TestGetPlugins_WhenManifestExists_FillsPluginFieldsAndSkipsManifestFile(t *testing.T) {
+	oldgetenv := file.Osgetenv
+	defer func() { file.Osgetenv = oldgetenv }()
+	file.Osgetenv = func(key string) string {
+		return "/usr/local/go/bin"
+	}
+	oldreaddir := file.Osreaddir
+	defer func() { file.Osreaddir = oldreaddir }()
+	file.Osreaddir = func(name string) ([]fs.DirEntry, error) {
+		return []fs.DirEntry{
+			MockDirEntry{name: "vision-plugin-myplugin-v2"},
+			MockDirEntry{name: "vision-plugin-myplugin-v2.yaml"},
+		}, nil
+	}
+	oldreadfile := file.Osreadfile
+	defer func() { file.Osreadfile = oldreadfile }()
+	file.Osreadfile = mockReadFile(map[string]string{"/usr/local/go/bin/vision-plugin-myplugin-v2.yaml": yamlManifest})
+
+	e := mocks.NewMockExecutor()
+	result, err := plugins.GetPlugins(&e)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "v1.2.0", result[0].Version)
+	assert.Equal(t, "Generates services", result[0].Description)
+}
+
+func TestDescribe_ParsesPluginOutput(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutput(`{"version":"v1.0.0","description":"described","commands":["create"]}`)
+	p, err := plugins.Describe(plugins.Plugin{Name: "vision-plugin-a-v1", PluginPath: "/bin/vision-plugin-a-v1"}, &e)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", p.Version)
+	assert.Equal(t, "described", p.Description)
+	assert.Equal(t, []string{"describing plugin vision-plugin-a-v1"}, e.History())
+}
+
+func TestDescribe_WhenPluginFails_ReturnsError(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	e.SetOutputErr(&exec.ExitError{})
+	_, err := plugins.Describe(plugins.Plugin{Name: "vision-plugin-a-v1", PluginPath: "/bin/vision-plugin-a-v1"}, &e)
+	require.Error(t, err)
+}
+
+func TestFilter_KeepsPluginsThatSupportCommandAndHostVersion(t *testing.T) {
+	all := []plugins.Plugin{
+		{Name: "a", Commands: []string{"create"}},
+		{Name: "b", Commands: []string{"create"}, MinHostVersion: "v2.0.0"},
+		{Name: "c", Commands: []string{"delete"}},
+	}
+	result := plugins.Filter(all, func(p plugins.Plugin) bool {
+		return p.SupportsCommand("create") && p.CompatibleWith("v1.4.0")
+	})
+	require.Len(t, result, 1)
+	assert.Equal(t, "a", result[0].Name)
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"1.2.3", "v1.2.3", 0},
+		{"v1.2", "v1.2.0", 0},
+		{"v1.10.0", "v1.9.0", 1},
+		{"v0.9.9", "v1.0.0", -1},
+		{"v1.0.0-rc1", "v1.0.0", -1},
+		{"v1.0.0+build", "v1.0.0", 0},
+	} {
+		assert.Equal(t, test.expected, plugins.CompareVersions(test.a, test.b), fmt.Sprintf("%s vs %s", test.a, test.b))
+	}
+}
+
+func TestLoadManifest_WhenJsonSidecarHasUnknownField_ReturnsError(t *testing.T) {
+	old := file.Osreadfile
+	defer func() { file.Osreadfile = old }()
+	file.Osreadfile = mockReadFile(map[string]string{"/bin/vision-plugin-a-v1.json": `{"version":"v2.0.0","unknown":"field"}`})
+
+	_, _, err := plugins.LoadManifest("/bin/vision-plugin-a-v1")
+	require.Error(t, err)
+}
+
+func TestGetPlugins_WhenManifestIsInvalid_WarnsAndKeepsPlugin(t *testing.T) {
+	oldgetenv := file.Osgetenv
+	defer func() { file.Osgetenv = oldgetenv }()
+	file.Osgetenv = func(key string) string {
+		return "/usr/local/go/bin"
+	}
+	oldreaddir := file.Osreaddir
+	defer func() { file.Osreaddir = oldreaddir }()
+	file.Osreaddir = func(name string) ([]fs.DirEntry, error) {
+		return []fs.DirEntry{
+			MockDirEntry{name: "vision-plugin-broken-v1"},
+			MockDirEntry{name: "vision-plugin-myplugin-v2"},
+		}, nil
+	}
+	oldreadfile := file.Osreadfile
+	defer func() { file.Osreadfile = oldreadfile }()
+	file.Osreadfile = mockReadFile(map[string]string{
+		"/usr/local/go/bin/vision-plugin-broken-v1.yaml":   "unknown: field\n",
+		"/usr/local/go/bin/vision-plugin-myplugin-v2.yaml": yamlManifest,
+	})
+
+	e := mocks.NewMockExecutor()
+	var warnings []string
+	p, err := plugins.GetPlugins(&e, plugins.WithRegistry(plugins.NewRegistry()), plugins.WithWarnings(func(msg string) {
+		warnings = append(warnings, msg)
+	}))
+	require.NoError(t, err)
+	require.Len(t, p, 2)
+	assert.Equal(t, "vision-plugin-broken-v1", p[0].Name)
+	assert.Empty(t, p[0].Version)
+	assert.Equal(t, "v1.2.0", p[1].Version)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "vision-plugin-broken-v1.yaml")
+}
+
+func TestDescribe_WhenPluginDoesNotSupportDescribe_ReturnsErrorAndKeepsManifest(t *testing.T) {
+	plugin := plugins.Plugin{Name: "vision-plugin-a-v1", PluginPath: "/bin/vision-plugin-a-v1", Version: "1.2.3"}
+	for _, output := range []string{`{"Result":"","Error":"unknown command"}`, `{"Result":"usage"}`} {
+		e := mocks.NewMockExecutor()
+		e.SetOutput(output)
+		p, err := plugins.Describe(plugin, &e)
+		require.Error(t, err, output)
+		assert.Equal(t, "1.2.3", p.Version, output)
+	}
+
+	e := mocks.NewMockExecutor()
+	e.SetOutput(`{"Result":"","Error":"unknown command"}`)
+	_, err := plugins.Describe(plugin, &e)
+	assert.EqualError(t, err, "describing plugin vision-plugin-a-v1: unknown command")
+}
@@ -0,0 +1,66 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+)
+
+// Option configures plugin discovery.
+type Option func(*options)
+
+type options struct {
+	searchPaths []string
+	projectDir  string
+	warn        func(msg string)
+	verifier    *Verifier
+	registry    *Registry
+}
+
+// WithSearchPaths searches dirs, in order, before any other plugin directory.
+func WithSearchPaths(dirs ...string) Option {
+	return func(o *options) {
+		o.searchPaths = append(o.searchPaths, dirs...)
+	}
+}
+
+// WithProjectDir searches the tools directory of projectDir after any WithSearchPaths directories.
+func WithProjectDir(projectDir string) Option {
+	return func(o *options) {
+		o.projectDir = projectDir
+	}
+}
+
+// WithWarnings reports discovery warnings, such as shadowed plugins, to warn instead of stderr.
+func WithWarnings(warn func(msg string)) Option {
+	return func(o *options) {
+		o.warn = warn
+	}
+}
+
+// WithVerifier checks each discovered plugin binary with v. Plugins that fail are reported as
+// warnings and, when v enforces verification, left out of the result.
+func WithVerifier(v *Verifier) Option {
+	return func(o *options) {
+		o.verifier = v
+	}
+}
+
+// WithRegistry takes internal plugins from r instead of DefaultRegistry.
+func WithRegistry(r *Registry) Option {
+	return func(o *options) {
+		o.registry = r
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		warn: func(msg string) {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
+		},
+		registry: DefaultRegistry,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
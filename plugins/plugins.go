@@ -1,8 +1,9 @@
 package plugins
 
 import (
+	"errors"
 	"fmt"
-	"os/exec"
+	"io/fs"
 	"path/filepath"
 	"strings"
 
@@ -18,52 +19,92 @@ const (
 	visionSecondWord = "plugin"
 )
 
+// Handler handles a serialized plugin request and returns the serialized response.
+// It is the in-process equivalent of a plugin binary's stdin and stdout.
+type Handler func(input string, e execute.Executor, t tmpl.TmplWriter) string
+
 type Plugin struct {
 	Name            string
 	PluginPath      string
-	InternalCommand func(input string, e execute.Executor, t tmpl.TmplWriter) string
-}
+	InternalCommand Handler
 
-var InternalPlugins = []Plugin{}
+	// Directory the plugin was found in, and paths of same-named plugins it shadows
+	SearchPath string
+	Shadows    []string
+
+	// Fields from the plugin manifest, empty if the plugin has none
+	Version        string
+	Description    string
+	Author         string
+	Commands       []string
+	MinHostVersion string
+}
 
-func GetPlugins(executor execute.Executor) ([]Plugin, error) {
+// GetPlugins returns the vision plugins found in the plugin search directories, followed by the
+// internal plugins of the registry. Internal plugins override external plugins of the same name.
+// Plugins whose manifest cannot be read are returned without its fields, with a warning.
+func GetPlugins(executor execute.Executor, opts ...Option) ([]Plugin, error) {
+	o := newOptions(opts)
+	internal := o.registry.Plugins()
 	var plugins []Plugin
-	pluginPath, err := goBinPath(executor)
+	dirs, err := searchDirs(executor, o)
 	if err != nil {
 		return plugins, err
 	}
-	pluginFiles, err := file.ReadDir(pluginPath)
-	if err != nil {
-		return plugins, fmt.Errorf("cannot read plugin directory %s: %s", pluginPath, err.Error())
-	}
-	for _, pluginFile := range pluginFiles {
-		if !pluginFile.IsDir() && fileIsVisionPlugin(pluginFile.Name()) && !fileIsInternalPlugin(pluginFile.Name()) {
-			plugins = append(plugins, Plugin{
+	found := map[string]int{}
+	for _, dir := range dirs {
+		pluginFiles, err := file.ReadDir(dir.path)
+		if err != nil {
+			if !dir.required && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return plugins, fmt.Errorf("cannot read plugin directory %s: %s", dir.path, err.Error())
+		}
+		for _, pluginFile := range pluginFiles {
+			if pluginFile.IsDir() || !fileIsVisionPlugin(pluginFile.Name()) || fileIsInternalPlugin(pluginFile.Name(), internal) {
+				continue
+			}
+			plugin := Plugin{
 				Name:            pluginFile.Name(),
-				PluginPath:      filepath.Join(pluginPath, pluginFile.Name()),
+				PluginPath:      filepath.Join(dir.path, pluginFile.Name()),
 				InternalCommand: nil,
-			})
+				SearchPath:      dir.path,
+			}
+			if i, ok := found[plugin.Name]; ok {
+				plugins[i].Shadows = append(plugins[i].Shadows, plugin.PluginPath)
+				o.warn(fmt.Sprintf("plugin %s is shadowed by %s", plugin.PluginPath, plugins[i].PluginPath))
+				continue
+			}
+			if o.verifier != nil {
+				if err := o.verifier.Verify(plugin); err != nil {
+					o.warn(err.Error())
+					if o.verifier.Mode == VerifyEnforce {
+						continue
+					}
+				}
+			}
+			manifest, ok, err := LoadManifest(plugin.PluginPath)
+			if err != nil {
+				o.warn(err.Error())
+			} else if ok {
+				manifest.apply(&plugin)
+			}
+			found[plugin.Name] = len(plugins)
+			plugins = append(plugins, plugin)
 		}
 	}
 
-	plugins = append(plugins, InternalPlugins...)
+	plugins = append(plugins, internal...)
 
 	return plugins, nil
 }
 
-func goBinPath(executor execute.Executor) (string, error) {
-	goBinPath := file.GetEnv(goBinEnvVar)
-	if goBinPath == "" {
-		goPath, err := executor.Output(exec.Command("go", "env", "GOPATH"), ".", "getting GOPATH")
-		if err != nil {
-			return "", err
+func fileIsVisionPlugin(filename string) bool {
+	for _, ext := range manifestExtensions {
+		if filepath.Ext(filename) == ext {
+			return false
 		}
-		goBinPath = string(goPath)[:len(goPath)-1] + "/bin"
 	}
-	return goBinPath, nil
-}
-
-func fileIsVisionPlugin(filename string) bool {
 	c := strings.Split(filename, visionSeparator)
 	if len(c) != 4 || c[0] != visionFirstWord || c[1] != visionSecondWord {
 		return false
@@ -71,8 +112,8 @@ func fileIsVisionPlugin(filename string) bool {
 	return true
 }
 
-func fileIsInternalPlugin(filename string) bool {
-	for _, internalPlugin := range InternalPlugins {
+func fileIsInternalPlugin(filename string, internal []Plugin) bool {
+	for _, internalPlugin := range internal {
 		if filename == internalPlugin.Name {
 			return true
 		}
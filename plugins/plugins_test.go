@@ -72,7 +72,7 @@ func TestGoGetPlugins_ReturnsAllValidPlugins(t *testing.T) {
 	e := mocks.NewMockExecutor()
 	result, err := plugins.GetPlugins(&e)
 	require.NoError(t, err)
-	assert.Equal(t, []plugins.Plugin{{"vision-plugin-myplugin-v2", "/usr/local/go/bin/vision-plugin-myplugin-v2", nil}}, result)
+	assert.Equal(t, []plugins.Plugin{{Name: "vision-plugin-myplugin-v2", PluginPath: "/usr/local/go/bin/vision-plugin-myplugin-v2", SearchPath: "/usr/local/go/bin"}}, result)
 }
 
 func TestGoGetPlugins_ReturnsInternalPlugins(t *testing.T) {
@@ -86,18 +86,15 @@ func TestGoGetPlugins_ReturnsInternalPlugins(t *testing.T) {
 	defer func() { file.Osreaddir = oldreaddir }()
 	file.Osreaddir = mockReadDir
 
-	oldInternalPlugins := plugins.InternalPlugins
-	defer func() { plugins.InternalPlugins = oldInternalPlugins }()
-
-	plugins.InternalPlugins = append(plugins.InternalPlugins,
-		plugins.Plugin{
-			Name:            "vision-plugin-myinternalplugin-v1",
-			PluginPath:      "",
-			InternalCommand: dummyPluginHandler,
-		})
+	registry := plugins.NewRegistry()
+	require.NoError(t, registry.Register(plugins.Plugin{
+		Name:            "vision-plugin-myinternalplugin-v1",
+		PluginPath:      "",
+		InternalCommand: dummyPluginHandler,
+	}))
 
 	e := mocks.NewMockExecutor()
-	result, err := plugins.GetPlugins(&e)
+	result, err := plugins.GetPlugins(&e, plugins.WithRegistry(registry))
 	require.NoError(t, err)
 	assert.Equal(t, 2, len(result))
 	assert.Equal(t, "vision-plugin-myplugin-v2", result[0].Name)
@@ -115,18 +112,15 @@ func TestGoGetPlugins_ReturnsInternalOverridesExternalPlugin(t *testing.T) {
 	defer func() { file.Osreaddir = oldreaddir }()
 	file.Osreaddir = mockReadDir
 
-	oldInternalPlugins := plugins.InternalPlugins
-	defer func() { plugins.InternalPlugins = oldInternalPlugins }()
-
-	plugins.InternalPlugins = append(plugins.InternalPlugins,
-		plugins.Plugin{
-			Name:            "vision-plugin-myplugin-v2",
-			PluginPath:      "",
-			InternalCommand: dummyPluginHandler,
-		})
+	registry := plugins.NewRegistry()
+	require.NoError(t, registry.Register(plugins.Plugin{
+		Name:            "vision-plugin-myplugin-v2",
+		PluginPath:      "",
+		InternalCommand: dummyPluginHandler,
+	}))
 
 	e := mocks.NewMockExecutor()
-	result, err := plugins.GetPlugins(&e)
+	result, err := plugins.GetPlugins(&e, plugins.WithRegistry(registry))
 	require.NoError(t, err)
 	assert.Equal(t, 1, len(result))
 	assert.Equal(t, "vision-plugin-myplugin-v2", result[0].Name)
@@ -0,0 +1,105 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrDuplicatePlugin is returned by Register when a plugin of the same name is already registered.
+var ErrDuplicatePlugin = errors.New("plugin already registered")
+
+// Registry holds the internal plugins that run in the host process.
+// Internal plugins are returned by GetPlugins after the external plugins, and override external
+// plugins of the same name. A Registry is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	plugins []Plugin
+}
+
+// DefaultRegistry is the registry GetPlugins uses unless WithRegistry is given.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds plugin, which must have a name and an InternalCommand.
+// It fails with ErrDuplicatePlugin if a plugin of the same name is already registered.
+func (r *Registry) Register(plugin Plugin) error {
+	if err := validInternal(plugin); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.index(plugin.Name) >= 0 {
+		return fmt.Errorf("cannot register plugin %s: %w", plugin.Name, ErrDuplicatePlugin)
+	}
+	r.plugins = append(r.plugins, plugin)
+	return nil
+}
+
+// Override is like Register but replaces any plugin of the same name, keeping its position.
+// It returns true if a plugin was replaced.
+func (r *Registry) Override(plugin Plugin) (bool, error) {
+	if err := validInternal(plugin); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.index(plugin.Name); i >= 0 {
+		r.plugins[i] = plugin
+		return true, nil
+	}
+	r.plugins = append(r.plugins, plugin)
+	return false, nil
+}
+
+// Unregister removes the plugin called name, returning false if it was not registered.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.index(name)
+	if i < 0 {
+		return false
+	}
+	r.plugins = append(r.plugins[:i:i], r.plugins[i+1:]...)
+	return true
+}
+
+// Lookup returns the plugin called name.
+func (r *Registry) Lookup(name string) (Plugin, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if i := r.index(name); i >= 0 {
+		return r.plugins[i], true
+	}
+	return Plugin{}, false
+}
+
+// Plugins returns the registered plugins in the order they were registered.
+func (r *Registry) Plugins() []Plugin {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Plugin{}, r.plugins...)
+}
+
+func (r *Registry) index(name string) int {
+	for i, p := range r.plugins {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func validInternal(plugin Plugin) error {
+	if plugin.Name == "" {
+		return fmt.Errorf("cannot register plugin without a name")
+	}
+	if plugin.InternalCommand == nil {
+		return fmt.Errorf("cannot register plugin %s without an internal command", plugin.Name)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package plugins_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/plugins"
+	"github.com/vision-cli/common/tmpl"
+)
+
+func internalPlugin(name string) plugins.Plugin {
+	return plugins.Plugin{Name: name, InternalCommand: dummyPluginHandler}
+}
+
+func TestRegistry_Register_AddsPluginsInOrder(t *testing.T) {
+	r := plugins.NewRegistry()
+	require.NoError(t, r.Register(internalPlugin("a")))
+	require.NoError(t, r.Register(internalPlugin("b")))
+
+	result := r.Plugins()
+	require.Len(t, result, 2)
+	assert.Equal(t, "a", result[0].Name)
+	assert.Equal(t, "b", result[1].Name)
+}
+
+func TestRegistry_Register_WithDuplicateName_ReturnsError(t *testing.T) {
+	r := plugins.NewRegistry()
+	require.NoError(t, r.Register(internalPlugin("a")))
+
+	err := r.Register(internalPlugin("a"))
+	assert.ErrorIs(t, err, plugins.ErrDuplicatePlugin)
+	assert.Len(t, r.Plugins(), 1)
+}
+
+func TestRegistry_Register_WithoutNameOrCommand_ReturnsError(t *testing.T) {
+	r := plugins.NewRegistry()
+	assert.Error(t, r.Register(plugins.Plugin{InternalCommand: dummyPluginHandler}))
+	assert.Error(t, r.Register(plugins.Plugin{Name: "a"}))
+	assert.Empty(t, r.Plugins())
+}
+
+func TestRegistry_Override_ReplacesPluginInPlace(t *testing.T) {
+	r := plugins.NewRegistry()
+	require.NoError(t, r.Register(internalPlugin("a")))
+	require.NoError(t, r.Register(internalPlugin("b")))
+
+	replacement := internalPlugin("a")
+	replacement.Version = "v2"
+	replaced, err := r.Override(replacement)
+	require.NoError(t, err)
+	assert.True(t, replaced)
+
+	result := r.Plugins()
+	require.Len(t, result, 2)
+	assert.Equal(t, "v2", result[0].Version)
+	assert.Equal(t, "b", result[1].Name)
+}
+
+func TestRegistry_Override_WithNewName_AddsPlugin(t *testing.T) {
+	r := plugins.NewRegistry()
+	replaced, err := r.Override(internalPlugin("a"))
+	require.NoError(t, err)
+	assert.False(t, replaced)
+	assert.Len(t, r.Plugins(), 1)
+}
+
+func TestRegistry_UnregisterAndLookup(t *testing.T) {
+	r := plugins.NewRegistry()
+	require.NoError(t, r.Register(internalPlugin("a")))
+
+	p, ok := r.Lookup("a")
+	assert.True(t, ok)
+	assert.Equal(t, "a", p.Name)
+
+	assert.True(t, r.Unregister("a"))
+	assert.False(t, r.Unregister("a"))
+	_, ok = r.Lookup("a")
+	assert.False(t, ok)
+}
+
+func TestRegistry_Plugins_ReturnsCopy(t *testing.T) {
+	r := plugins.NewRegistry()
+	require.NoError(t, r.Register(internalPlugin("a")))
+
+	result := r.Plugins()
+	result[0].Name = "changed"
+	_, ok := r.Lookup("a")
+	assert.True(t, ok)
+}
+
+func TestRegistry_IsSafeForConcurrentUse(t *testing.T) {
+	r := plugins.NewRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("plugin-%d", i)
+			assert.NoError(t, r.Register(plugins.Plugin{
+				Name: name,
+				InternalCommand: func(string, execute.Executor, tmpl.TmplWriter) string {
+					return name
+				},
+			}))
+			_, ok := r.Lookup(name)
+			assert.True(t, ok)
+			_ = r.Plugins()
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, r.Plugins(), 50)
+}
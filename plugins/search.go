@@ -0,0 +1,87 @@
+package plugins
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/file"
+)
+
+const (
+	// PathEnvVar lists extra plugin directories, separated like PATH.
+	PathEnvVar = "VISION_PLUGIN_PATH"
+
+	// projectPluginDir is the plugin directory relative to a project set with WithProjectDir.
+	projectPluginDir = "tools"
+)
+
+// searchDir is a directory searched for plugins.
+// Missing directories are skipped unless they are required.
+type searchDir struct {
+	path     string
+	required bool
+}
+
+// searchDirs returns the plugin directories in precedence order: WithSearchPaths directories,
+// the project tools directory, PathEnvVar directories and finally $GOBIN or $GOPATH/bin.
+// A plugin found in an earlier directory shadows plugins of the same name in later ones.
+// The Go bin directory is required unless WithSearchPaths or WithProjectDir is used, in which case
+// it is skipped if it is missing or cannot be found.
+func searchDirs(executor execute.Executor, o *options) ([]searchDir, error) {
+	var dirs []searchDir
+	for _, dir := range o.searchPaths {
+		dirs = append(dirs, searchDir{path: dir, required: true})
+	}
+	if o.projectDir != "" {
+		dirs = append(dirs, searchDir{path: filepath.Join(o.projectDir, projectPluginDir)})
+	}
+	for _, dir := range filepath.SplitList(file.GetEnv(PathEnvVar)) {
+		if dir != "" {
+			dirs = append(dirs, searchDir{path: dir})
+		}
+	}
+	optional := len(o.searchPaths) > 0 || o.projectDir != ""
+	goBin, err := goBinPath(executor)
+	switch {
+	case err == nil:
+		dirs = append(dirs, searchDir{path: goBin, required: !optional})
+	case !optional:
+		return nil, err
+	}
+	return dedupeDirs(dirs), nil
+}
+
+// dedupeDirs keeps the first occurrence of each directory, required if any occurrence is.
+func dedupeDirs(dirs []searchDir) []searchDir {
+	var unique []searchDir
+	index := map[string]int{}
+	for _, dir := range dirs {
+		clean := filepath.Clean(dir.path)
+		if i, ok := index[clean]; ok {
+			unique[i].required = unique[i].required || dir.required
+			continue
+		}
+		index[clean] = len(unique)
+		unique = append(unique, searchDir{path: clean, required: dir.required})
+	}
+	return unique
+}
+
+func goBinPath(executor execute.Executor) (string, error) {
+	goBinPath := file.GetEnv(goBinEnvVar)
+	if goBinPath == "" {
+		goPath, err := executor.Output(exec.Command("go", "env", "GOPATH"), ".", "getting GOPATH")
+		if err != nil {
+			return "", err
+		}
+		goPath = strings.TrimSpace(goPath)
+		if goPath == "" {
+			return "", fmt.Errorf("cannot find plugin directory: neither %s nor GOPATH is set", goBinEnvVar)
+		}
+		goBinPath = filepath.Join(goPath, "bin")
+	}
+	return goBinPath, nil
+}
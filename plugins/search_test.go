@@ -0,0 +1,126 @@
+package plugins_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vision-cli/common/file"
+	"github.com/vision-cli/common/mocks"
+	"github.com/vision-cli/common/plugins"
+)
+
+func makePluginDir(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o755))
+	}
+	return dir
+}
+
+func mockEnv(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+func TestGetPlugins_SearchesAllPathsInPrecedenceOrder(t *testing.T) {
+	explicit := makePluginDir(t, "vision-plugin-a-v1")
+	project := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(project, "tools"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(project, "tools", "vision-plugin-b-v1"), nil, 0o755))
+	shared := makePluginDir(t, "vision-plugin-c-v1")
+	goBin := makePluginDir(t, "vision-plugin-d-v1")
+
+	old := file.Osgetenv
+	defer func() { file.Osgetenv = old }()
+	file.Osgetenv = mockEnv(map[string]string{"GOBIN": goBin, plugins.PathEnvVar: shared})
+
+	e := mocks.NewMockExecutor()
+	result, err := plugins.GetPlugins(&e, plugins.WithSearchPaths(explicit), plugins.WithProjectDir(project))
+	require.NoError(t, err)
+	require.Len(t, result, 4)
+	assert.Equal(t, explicit, result[0].SearchPath)
+	assert.Equal(t, filepath.Join(project, "tools"), result[1].SearchPath)
+	assert.Equal(t, shared, result[2].SearchPath)
+	assert.Equal(t, goBin, result[3].SearchPath)
+	assert.Equal(t, filepath.Join(goBin, "vision-plugin-d-v1"), result[3].PluginPath)
+}
+
+func TestGetPlugins_WhenPluginIsDuplicated_EarlierPathShadowsLaterAndWarns(t *testing.T) {
+	shared := makePluginDir(t, "vision-plugin-a-v1")
+	goBin := makePluginDir(t, "vision-plugin-a-v1")
+
+	old := file.Osgetenv
+	defer func() { file.Osgetenv = old }()
+	file.Osgetenv = mockEnv(map[string]string{"GOBIN": goBin, plugins.PathEnvVar: shared})
+
+	var warnings []string
+	e := mocks.NewMockExecutor()
+	result, err := plugins.GetPlugins(&e, plugins.WithWarnings(func(msg string) {
+		warnings = append(warnings, msg)
+	}))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, shared, result[0].SearchPath)
+	assert.Equal(t, []string{filepath.Join(goBin, "vision-plugin-a-v1")}, result[0].Shadows)
+	require.Len(t, warnings, 1)
+	assert.True(t, strings.Contains(warnings[0], "shadowed"))
+}
+
+func TestGetPlugins_WhenOptionalPathIsMissing_SkipsIt(t *testing.T) {
+	goBin := makePluginDir(t, "vision-plugin-a-v1")
+
+	old := file.Osgetenv
+	defer func() { file.Osgetenv = old }()
+	missing := filepath.Join(t.TempDir(), "missing")
+	file.Osgetenv = mockEnv(map[string]string{"GOBIN": goBin, plugins.PathEnvVar: missing})
+
+	e := mocks.NewMockExecutor()
+	result, err := plugins.GetPlugins(&e, plugins.WithProjectDir(missing))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+}
+
+func TestGetPlugins_WhenExplicitPathIsMissing_ReturnsError(t *testing.T) {
+	goBin := makePluginDir(t)
+
+	old := file.Osgetenv
+	defer func() { file.Osgetenv = old }()
+	file.Osgetenv = mockEnv(map[string]string{"GOBIN": goBin})
+
+	e := mocks.NewMockExecutor()
+	_, err := plugins.GetPlugins(&e, plugins.WithSearchPaths(filepath.Join(t.TempDir(), "missing")))
+	require.Error(t, err)
+}
+
+func TestGetPlugins_WhenGoBinIsMissingButOtherPathsAreSet_SkipsIt(t *testing.T) {
+	project := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(project, "tools"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(project, "tools", "vision-plugin-a-v1"), nil, 0o755))
+
+	old := file.Osgetenv
+	defer func() { file.Osgetenv = old }()
+	file.Osgetenv = mockEnv(map[string]string{"GOBIN": filepath.Join(t.TempDir(), "missing")})
+
+	e := mocks.NewMockExecutor()
+	result, err := plugins.GetPlugins(&e, plugins.WithProjectDir(project), plugins.WithRegistry(plugins.NewRegistry()))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "vision-plugin-a-v1", result[0].Name)
+}
+
+func TestGetPlugins_WhenGoPathIsEmpty_ReturnsError(t *testing.T) {
+	old := file.Osgetenv
+	defer func() { file.Osgetenv = old }()
+	file.Osgetenv = mockEnv(nil)
+
+	e := mocks.NewMockExecutor()
+	e.SetOutput("\n")
+	_, err := plugins.GetPlugins(&e, plugins.WithRegistry(plugins.NewRegistry()))
+	require.EqualError(t, err, "cannot find plugin directory: neither GOBIN nor GOPATH is set")
+}
@@ -0,0 +1,188 @@
+package plugins
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/vision-cli/common/file"
+)
+
+const (
+	// ChecksumFile is the conventional name of a plugin checksum file.
+	ChecksumFile = "vision-plugins.sum"
+
+	// SignatureExtension is appended to a checksum file path to find its signature.
+	SignatureExtension = ".sig"
+)
+
+// VerifyMode decides what happens to plugins that fail verification.
+type VerifyMode int
+
+const (
+	// VerifyWarn reports plugins that fail verification but still allows them to run.
+	VerifyWarn VerifyMode = iota
+	// VerifyEnforce reports plugins that fail verification and refuses to run them.
+	VerifyEnforce
+)
+
+// Reasons a plugin fails verification.
+const (
+	ReasonUnknown  = "unknown"
+	ReasonModified = "modified"
+)
+
+// Checksums maps plugin names to the hex-encoded SHA-256 checksums of their binaries.
+type Checksums map[string]string
+
+// Verifier checks plugin binaries against known checksums before they are run.
+type Verifier struct {
+	Checksums Checksums
+	Mode      VerifyMode
+}
+
+// VerificationError is returned for a plugin binary that is not in the checksums or has changed.
+type VerificationError struct {
+	Plugin   string
+	Path     string
+	Reason   string // ReasonUnknown or ReasonModified
+	Expected string // checksum recorded for the plugin, empty if unknown
+	Actual   string // checksum of the plugin binary
+}
+
+func (e *VerificationError) Error() string {
+	if e.Reason == ReasonUnknown {
+		return fmt.Sprintf("plugin %s at %s has no known checksum", e.Plugin, e.Path)
+	}
+	return fmt.Sprintf("plugin %s at %s has been modified: checksum %s, expected %s", e.Plugin, e.Path, e.Actual, e.Expected)
+}
+
+// Verify checks the binary of plugin against v's checksums. Internal plugins always pass.
+func (v *Verifier) Verify(plugin Plugin) error {
+	if plugin.InternalCommand != nil {
+		return nil
+	}
+	actual, err := Checksum(plugin.PluginPath)
+	if err != nil {
+		return fmt.Errorf("verifying plugin %s: %w", plugin.Name, err)
+	}
+	expected, ok := v.Checksums[plugin.Name]
+	if !ok {
+		return &VerificationError{Plugin: plugin.Name, Path: plugin.PluginPath, Reason: ReasonUnknown, Actual: actual}
+	}
+	if !strings.EqualFold(expected, actual) {
+		return &VerificationError{Plugin: plugin.Name, Path: plugin.PluginPath, Reason: ReasonModified, Expected: expected, Actual: actual}
+	}
+	return nil
+}
+
+// Checksum returns the hex-encoded SHA-256 checksum of the file at path.
+func Checksum(path string) (string, error) {
+	f, err := file.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReadChecksums reads a checksum file in sha256sum format, one "<checksum>  <plugin name>" per line.
+// If publicKey is not nil, the file must have a valid ed25519 signature in path+SignatureExtension.
+func ReadChecksums(path string, publicKey ed25519.PublicKey) (Checksums, error) {
+	if publicKey != nil && len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("reading plugin checksums: public key is %d bytes, expected %d", len(publicKey), ed25519.PublicKeySize)
+	}
+	data, err := file.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading plugin checksums: %w", err)
+	}
+	if publicKey != nil {
+		if err := verifySignature(path, data, publicKey); err != nil {
+			return nil, err
+		}
+	}
+	return ParseChecksums(string(data))
+}
+
+// ParseChecksums parses checksums in sha256sum format.
+func ParseChecksums(data string) (Checksums, error) {
+	checksums := Checksums{}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("plugin checksums line %d: expected checksum and name", line)
+		}
+		sum, name := fields[0], strings.TrimPrefix(fields[1], "*")
+		if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("plugin checksums line %d: invalid SHA-256 checksum %q", line, sum)
+		}
+		checksums[name] = strings.ToLower(sum)
+	}
+	return checksums, scanner.Err()
+}
+
+// String formats the checksums in sha256sum format, sorted by plugin name.
+func (c Checksums) String() string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", c[name], name)
+	}
+	return b.String()
+}
+
+// WriteChecksums writes c to path. If privateKey is not nil, a signature is written to
+// path+SignatureExtension.
+func WriteChecksums(path string, c Checksums, privateKey ed25519.PrivateKey) error {
+	if privateKey != nil && len(privateKey) != ed25519.PrivateKeySize {
+		return fmt.Errorf("writing plugin checksums: private key is %d bytes, expected %d", len(privateKey), ed25519.PrivateKeySize)
+	}
+	data := []byte(c.String())
+	if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gomnd //permission mask has inherent meaning
+		return fmt.Errorf("writing plugin checksums: %w", err)
+	}
+	if privateKey == nil {
+		return nil
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, data))
+	if err := os.WriteFile(path+SignatureExtension, []byte(signature+"\n"), 0o644); err != nil { //nolint:gomnd //permission mask has inherent meaning
+		return fmt.Errorf("writing plugin checksums signature: %w", err)
+	}
+	return nil
+}
+
+func verifySignature(path string, data []byte, publicKey ed25519.PublicKey) error {
+	encoded, err := file.ReadFile(path + SignatureExtension)
+	if err != nil {
+		return fmt.Errorf("reading plugin checksums signature: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return fmt.Errorf("decoding plugin checksums signature: %w", err)
+	}
+	if !ed25519.Verify(publicKey, data, signature) {
+		return fmt.Errorf("plugin checksums %s do not match their signature", path)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package plugins_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vision-cli/common/file"
+	"github.com/vision-cli/common/mocks"
+	"github.com/vision-cli/common/plugins"
+)
+
+// a well-formed checksum that matches none of the test files
+const otherChecksum = "6e3a6a1ba4b9a2d4f1a8e09d6e3d2c4c0c0e1c3f3b9b1f9bd2b1b0c7b3f0e9a1"
+
+func TestChecksum_ReturnsSha256OfFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	require.NoError(t, os.WriteFile(path, []byte("hello\n"), 0o644))
+	sum, err := plugins.Checksum(path)
+	require.NoError(t, err)
+	assert.Equal(t, "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", sum)
+}
+
+func TestVerify_WhenChecksumMatches_ReturnsNil(t *testing.T) {
+	dir := makePluginDir(t, "vision-plugin-a-v1")
+	p := plugins.Plugin{Name: "vision-plugin-a-v1", PluginPath: filepath.Join(dir, "vision-plugin-a-v1")}
+	sum, err := plugins.Checksum(p.PluginPath)
+	require.NoError(t, err)
+
+	v := plugins.Verifier{Checksums: plugins.Checksums{p.Name: sum}}
+	assert.NoError(t, v.Verify(p))
+}
+
+func TestVerify_WhenPluginIsUnknownOrModified_ReturnsVerificationError(t *testing.T) {
+	dir := makePluginDir(t, "vision-plugin-a-v1", "vision-plugin-b-v1")
+	v := plugins.Verifier{Checksums: plugins.Checksums{"vision-plugin-a-v1": otherChecksum}}
+
+	var verr *plugins.VerificationError
+	err := v.Verify(plugins.Plugin{Name: "vision-plugin-a-v1", PluginPath: filepath.Join(dir, "vision-plugin-a-v1")})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, plugins.ReasonModified, verr.Reason)
+	assert.Equal(t, otherChecksum, verr.Expected)
+
+	err = v.Verify(plugins.Plugin{Name: "vision-plugin-b-v1", PluginPath: filepath.Join(dir, "vision-plugin-b-v1")})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, plugins.ReasonUnknown, verr.Reason)
+}
+
+func TestVerify_WhenPluginIsInternal_ReturnsNil(t *testing.T) {
+	v := plugins.Verifier{}
+	assert.NoError(t, v.Verify(plugins.Plugin{Name: "internal", InternalCommand: dummyPluginHandler}))
+}
+
+func TestParseChecksums_ReadsSha256sumFormat(t *testing.T) {
+	sums, err := plugins.ParseChecksums("# plugins\n" + otherChecksum + "  vision-plugin-a-v1\n" + otherChecksum + " *vision-plugin-b-v1\n")
+	require.NoError(t, err)
+	assert.Equal(t, plugins.Checksums{"vision-plugin-a-v1": otherChecksum, "vision-plugin-b-v1": otherChecksum}, sums)
+}
+
+func TestParseChecksums_WhenChecksumInvalid_ReturnsError(t *testing.T) {
+	_, err := plugins.ParseChecksums("abc  vision-plugin-a-v1\n")
+	require.Error(t, err)
+}
+
+func TestReadChecksums_WhenSigned_VerifiesSignature(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), plugins.ChecksumFile)
+	sums := plugins.Checksums{"vision-plugin-a-v1": otherChecksum}
+	require.NoError(t, plugins.WriteChecksums(path, sums, private))
+
+	read, err := plugins.ReadChecksums(path, public)
+	require.NoError(t, err)
+	assert.Equal(t, sums, read)
+
+	require.NoError(t, os.WriteFile(path, []byte(otherChecksum+"  vision-plugin-evil-v1\n"), 0o644))
+	_, err = plugins.ReadChecksums(path, public)
+	require.Error(t, err)
+}
+
+func TestReadChecksums_WhenKeyHasWrongLength_ReturnsError(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), plugins.ChecksumFile)
+	require.NoError(t, plugins.WriteChecksums(path, plugins.Checksums{"vision-plugin-a-v1": otherChecksum}, private))
+
+	_, err = plugins.ReadChecksums(path, ed25519.PublicKey("short"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "public key is 5 bytes")
+}
+
+func TestWriteChecksums_WhenKeyHasWrongLength_ReturnsError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), plugins.ChecksumFile)
+	err := plugins.WriteChecksums(path, plugins.Checksums{"vision-plugin-a-v1": otherChecksum}, ed25519.PrivateKey("short"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "private key is 5 bytes")
+	assert.NoFileExists(t, path)
+}
+
+func TestGetPlugins_WithEnforcingVerifier_LeavesOutUnverifiedPlugins(t *testing.T) {
+	goBin := makePluginDir(t, "vision-plugin-a-v1")
+	require.NoError(t, os.WriteFile(filepath.Join(goBin, "vision-plugin-b-v1"), []byte("tampered"), 0o755))
+	sum, err := plugins.Checksum(filepath.Join(goBin, "vision-plugin-a-v1"))
+	require.NoError(t, err)
+
+	old := file.Osgetenv
+	defer func() { file.Osgetenv = old }()
+	file.Osgetenv = mockEnv(map[string]string{"GOBIN": goBin})
+
+	v := &plugins.Verifier{Checksums: plugins.Checksums{"vision-plugin-a-v1": sum, "vision-plugin-b-v1": sum}, Mode: plugins.VerifyEnforce}
+	var warnings []string
+	e := mocks.NewMockExecutor()
+	result, err := plugins.GetPlugins(&e, plugins.WithVerifier(v), plugins.WithWarnings(func(msg string) {
+		warnings = append(warnings, msg)
+	}))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "vision-plugin-a-v1", result[0].Name)
+	assert.Len(t, warnings, 1)
+
+	v.Mode = plugins.VerifyWarn
+	result, err = plugins.GetPlugins(&e, plugins.WithVerifier(v), plugins.WithWarnings(func(string) {}))
+	require.NoError(t, err)
+	assert.Len(t, result, 2)
+}
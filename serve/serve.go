@@ -0,0 +1,187 @@
+package serve
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/comms"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/marshal"
+	"github.com/vision-cli/common/plugins"
+	"github.com/vision-cli/common/tmpl"
+)
+
+// jsonCodec is used for internal plugins, serve mode and hosts that do not name an encoding.
+var jsonCodec, _ = marshal.CodecFor(marshal.JSON)
+
+// CommandHandler handles one command of the plugin API.
+// The returned value is sent to the host as the response. A string is sent as the Result of an
+// api_v1.PluginResponse, and an error is sent as its Error.
+type CommandHandler func(request *api_v1.PluginRequest, e execute.Executor, t tmpl.TmplWriter) (any, error)
+
+// Server implements the plugin side of the host protocol, dispatching requests to command handlers.
+type Server struct {
+	handlers map[string]CommandHandler
+	manifest *plugins.Manifest
+}
+
+// NewServer returns a server with no command handlers.
+func NewServer() *Server {
+	return &Server{handlers: map[string]CommandHandler{}}
+}
+
+// Handle registers h for command, one of the api_v1 Command constants.
+func (s *Server) Handle(command string, h CommandHandler) *Server {
+	s.handlers[command] = h
+	return s
+}
+
+// Describe sets the manifest written when the host runs the plugin with plugins.DescribeEnvVar set.
+func (s *Server) Describe(m plugins.Manifest) *Server {
+	s.manifest = &m
+	return s
+}
+
+// Handler returns the server as a plugins.Handler, so the same plugin can be registered as an
+// internal plugin of the host.
+func (s *Server) Handler() plugins.Handler {
+	return func(input string, e execute.Executor, t tmpl.TmplWriter) string {
+		return s.handle(jsonCodec, input, e, t)
+	}
+}
+
+// Serve runs the plugin as a binary using stdin and stdout.
+func (s *Server) Serve() error {
+	return s.ServeIO(os.Stdin, os.Stdout, execute.NewOsExecutor(), tmpl.NewOsTmpWriter())
+}
+
+// ServeIO answers the host on stdin and stdout, in whichever mode the host set in the environment:
+// describe, handshake, serve or a single request. A single request is decoded, and its response
+// encoded, with the codec named in comms.EncodingEnvVar.
+func (s *Server) ServeIO(stdin io.Reader, stdout io.Writer, e execute.Executor, t tmpl.TmplWriter) error {
+	switch {
+	case os.Getenv(plugins.DescribeEnvVar) == "1":
+		return s.describe(stdout)
+	case os.Getenv(comms.HandshakeEnvVar) == "1":
+		return s.handshake(stdin, stdout)
+	case os.Getenv(comms.ServeEnvVar) == "1":
+		return s.serve(stdin, stdout, e, t)
+	}
+	codec := jsonCodec
+	if name := os.Getenv(comms.EncodingEnvVar); name != "" {
+		var err error
+		if codec, err = marshal.CodecFor(name); err != nil {
+			return err
+		}
+	}
+	input, err := io.ReadAll(stdin)
+	if err != nil {
+		return fmt.Errorf("reading request: %w", err)
+	}
+	_, err = io.WriteString(stdout, s.handle(codec, string(input), e, t))
+	return err
+}
+
+func (s *Server) describe(stdout io.Writer) error {
+	m := plugins.Manifest{}
+	if s.manifest != nil {
+		m = *s.manifest
+	}
+	if len(m.Commands) == 0 {
+		for command := range s.handlers {
+			m.Commands = append(m.Commands, command)
+		}
+		sort.Strings(m.Commands)
+	}
+	return json.NewEncoder(stdout).Encode(m)
+}
+
+func (s *Server) handshake(stdin io.Reader, stdout io.Writer) error {
+	if _, err := io.Copy(io.Discard, stdin); err != nil {
+		return fmt.Errorf("reading handshake: %w", err)
+	}
+	reply := comms.HelloReply{Capabilities: []string{comms.CapabilityServe}, Encodings: marshal.CodecNames()}
+	for _, f := range comms.DefaultRequestFormats() {
+		reply.Versions = append(reply.Versions, f.Version)
+	}
+	return json.NewEncoder(stdout).Encode(reply)
+}
+
+// serve answers requests concurrently until stdin is closed.
+func (s *Server) serve(stdin io.Reader, stdout io.Writer, e execute.Executor, t tmpl.TmplWriter) error {
+	var mu sync.Mutex
+	write := func(frame any) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return json.NewEncoder(stdout).Encode(frame)
+	}
+	if err := write(comms.ServeReady{Serve: true}); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	reader := bufio.NewReader(stdin)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			frame, decodeErr := marshal.Unmarshal[comms.ServeRequest](string(line))
+			if decodeErr != nil {
+				return fmt.Errorf("reading request: %w", decodeErr)
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				response := s.handle(jsonCodec, string(frame.Request), e, t)
+				_ = write(comms.ServeResponse{ID: frame.ID, Response: json.RawMessage(response)})
+			}()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading request: %w", err)
+		}
+	}
+}
+
+// handle decodes a request with codec, dispatches it and encodes the response.
+func (s *Server) handle(codec marshal.Codec, input string, e execute.Executor, t tmpl.TmplWriter) (response string) {
+	defer func() {
+		if r := recover(); r != nil {
+			response = errorResponse(codec, fmt.Errorf("plugin panicked: %v", r))
+		}
+	}()
+
+	request, err := marshal.UnmarshalWith[api_v1.PluginRequest](codec, input)
+	if err != nil {
+		return errorResponse(codec, fmt.Errorf("decoding request: %w", err))
+	}
+	h, ok := s.handlers[request.Command]
+	if !ok {
+		return errorResponse(codec, fmt.Errorf("unsupported command %q", request.Command))
+	}
+	result, err := h(&request, e, t)
+	if err != nil {
+		return errorResponse(codec, err)
+	}
+	if text, ok := result.(string); ok {
+		result = api_v1.PluginResponse{Result: text}
+	}
+	out, err := marshal.MarshalWith(codec, result)
+	if err != nil {
+		return errorResponse(codec, fmt.Errorf("encoding response: %w", err))
+	}
+	return out
+}
+
+func errorResponse(codec marshal.Codec, err error) string {
+	out, _ := marshal.MarshalWith(codec, api_v1.PluginResponse{Error: err.Error()})
+	return out
+}
@@ -0,0 +1,249 @@
+package serve_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api_v1 "github.com/vision-cli/api/v1"
+	"github.com/vision-cli/common/comms"
+	"github.com/vision-cli/common/execute"
+	"github.com/vision-cli/common/marshal"
+	"github.com/vision-cli/common/mocks"
+	"github.com/vision-cli/common/plugins"
+	"github.com/vision-cli/common/serve"
+	"github.com/vision-cli/common/tmpl"
+)
+
+// helperEnvVar makes the test binary serve testServer, so tests can run it as a real plugin.
+const helperEnvVar = "SERVE_TEST_HELPER_PLUGIN"
+
+type usageResponse struct {
+	Usage string
+}
+
+var testServer = serve.NewServer().
+	Handle(api_v1.CommandUsage, func(_ *api_v1.PluginRequest, _ execute.Executor, _ tmpl.TmplWriter) (any, error) {
+		return usageResponse{Usage: "test plugin"}, nil
+	}).
+	Handle(api_v1.CommandRun, func(r *api_v1.PluginRequest, _ execute.Executor, _ tmpl.TmplWriter) (any, error) {
+		switch {
+		case len(r.Args) == 0:
+			return nil, errors.New("nothing to run")
+		case r.Args[0] == "panic":
+			panic("boom")
+		}
+		return "ran " + strings.Join(r.Args, " "), nil
+	})
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnvVar) == "" {
+		os.Exit(m.Run())
+	}
+	if err := testServer.Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func helperPlugin(t *testing.T) plugins.Plugin {
+	t.Helper()
+	t.Setenv(helperEnvVar, "1")
+	return plugins.Plugin{Name: "test", PluginPath: os.Args[0]}
+}
+
+func handle(t *testing.T, request api_v1.PluginRequest) string {
+	t.Helper()
+	input, err := marshal.Marshal(request)
+	require.NoError(t, err)
+	e := mocks.NewMockExecutor()
+	w := mocks.NewMockTmplWriter()
+	return testServer.Handler()(input, &e, &w)
+}
+
+func TestHandler_WithRegisteredCommand_ReturnsHandlerResult(t *testing.T) {
+	assert.JSONEq(t, `{"Usage":"test plugin"}`, handle(t, api_v1.PluginRequest{Command: api_v1.CommandUsage}))
+}
+
+func TestHandler_WithStringResult_ReturnsPluginResponse(t *testing.T) {
+	response := handle(t, api_v1.PluginRequest{Command: api_v1.CommandRun, Args: []string{"a", "b"}})
+	assert.JSONEq(t, `{"Result":"ran a b","Error":""}`, response)
+}
+
+func TestHandler_WhenHandlerFails_ReturnsError(t *testing.T) {
+	response := handle(t, api_v1.PluginRequest{Command: api_v1.CommandRun})
+	assert.JSONEq(t, `{"Result":"","Error":"nothing to run"}`, response)
+}
+
+func TestHandler_WhenHandlerPanics_ReturnsError(t *testing.T) {
+	response := handle(t, api_v1.PluginRequest{Command: api_v1.CommandRun, Args: []string{"panic"}})
+	assert.JSONEq(t, `{"Result":"","Error":"plugin panicked: boom"}`, response)
+}
+
+func TestHandler_WithUnknownCommand_ReturnsError(t *testing.T) {
+	response := handle(t, api_v1.PluginRequest{Command: api_v1.CommandConfig})
+	assert.JSONEq(t, `{"Result":"","Error":"unsupported command \"config\""}`, response)
+}
+
+func TestHandler_WithInvalidRequest_ReturnsError(t *testing.T) {
+	e := mocks.NewMockExecutor()
+	w := mocks.NewMockTmplWriter()
+	response, err := marshal.Unmarshal[api_v1.PluginResponse](testServer.Handler()("not json", &e, &w))
+	require.NoError(t, err)
+	assert.Contains(t, response.Error, "decoding request")
+}
+
+func TestServeIO_WithDescribe_WritesManifest(t *testing.T) {
+	t.Setenv(plugins.DescribeEnvVar, "1")
+	s := serve.NewServer().
+		Handle(api_v1.CommandUsage, nil).
+		Handle(api_v1.CommandRun, nil).
+		Describe(plugins.Manifest{Version: "v1.2.0", Description: "test plugin"})
+	var out bytes.Buffer
+	e := mocks.NewMockExecutor()
+	w := mocks.NewMockTmplWriter()
+
+	require.NoError(t, s.ServeIO(strings.NewReader(""), &out, &e, &w))
+
+	var m plugins.Manifest
+	require.NoError(t, json.Unmarshal(out.Bytes(), &m))
+	assert.Equal(t, plugins.Manifest{
+		Version:     "v1.2.0",
+		Description: "test plugin",
+		Commands:    []string{api_v1.CommandRun, api_v1.CommandUsage},
+	}, m)
+}
+
+func TestServeIO_WithSingleRequest_WritesResponse(t *testing.T) {
+	input, err := marshal.Marshal(api_v1.PluginRequest{Command: api_v1.CommandRun, Args: []string{"x"}})
+	require.NoError(t, err)
+	var out bytes.Buffer
+	e := mocks.NewMockExecutor()
+	w := mocks.NewMockTmplWriter()
+
+	require.NoError(t, testServer.ServeIO(strings.NewReader(input), &out, &e, &w))
+	assert.JSONEq(t, `{"Result":"ran x","Error":""}`, out.String())
+}
+
+func TestServeIO_WithServe_AnswersEachRequest(t *testing.T) {
+	t.Setenv(comms.ServeEnvVar, "1")
+	var in strings.Builder
+	for id, arg := range []string{"one", "two"} {
+		request, err := marshal.Marshal(api_v1.PluginRequest{Command: api_v1.CommandRun, Args: []string{arg}})
+		require.NoError(t, err)
+		frame, err := marshal.Marshal(comms.ServeRequest{ID: uint64(id + 1), Request: json.RawMessage(request)})
+		require.NoError(t, err)
+		in.WriteString(frame + "\n")
+	}
+	var out bytes.Buffer
+	e := mocks.NewMockExecutor()
+	w := mocks.NewMockTmplWriter()
+
+	require.NoError(t, testServer.ServeIO(strings.NewReader(in.String()), &out, &e, &w))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.JSONEq(t, `{"Serve":true}`, lines[0])
+	results := map[uint64]string{}
+	for _, line := range lines[1:] {
+		frame, err := marshal.Unmarshal[comms.ServeResponse](line)
+		require.NoError(t, err)
+		response, err := marshal.Unmarshal[api_v1.PluginResponse](string(frame.Response))
+		require.NoError(t, err)
+		results[frame.ID] = response.Result
+	}
+	assert.Equal(t, map[uint64]string{1: "ran one", 2: "ran two"}, results)
+}
+
+func TestServer_AsInternalPlugin_AnswersCall(t *testing.T) {
+	plugin := plugins.Plugin{Name: "internal", InternalCommand: testServer.Handler()}
+	e := mocks.NewMockExecutor()
+
+	response, err := comms.Call[api_v1.PluginResponse](plugin, &api_v1.PluginRequest{Command: api_v1.CommandRun, Args: []string{"x"}}, &e)
+	require.NoError(t, err)
+	assert.Equal(t, "ran x", response.Result)
+}
+
+func TestServer_AsExternalPlugin_AnswersCall(t *testing.T) {
+	plugin := helperPlugin(t)
+
+	response, err := comms.Call[usageResponse](plugin, &api_v1.PluginRequest{Command: api_v1.CommandUsage}, execute.NewOsExecutor())
+	require.NoError(t, err)
+	assert.Equal(t, "test plugin", response.Usage)
+
+	failed, err := comms.Call[api_v1.PluginResponse](plugin, &api_v1.PluginRequest{Command: api_v1.CommandRun}, execute.NewOsExecutor())
+	require.NoError(t, err)
+	assert.Equal(t, "nothing to run", failed.Error)
+}
+
+func TestServer_AsExternalPlugin_AnswersInNegotiatedEncoding(t *testing.T) {
+	plugin := helperPlugin(t)
+	ctx := context.Background()
+
+	for _, encoding := range []string{marshal.CBOR, marshal.YAML} {
+		protocol, err := comms.Handshake(ctx, plugin, execute.NewOsExecutor(), comms.WithEncodings(encoding))
+		require.NoError(t, err)
+		assert.Equal(t, encoding, protocol.Encoding)
+
+		response, err := comms.Call[usageResponse](plugin, &api_v1.PluginRequest{Command: api_v1.CommandUsage}, execute.NewOsExecutor(), comms.WithProtocol(protocol))
+		require.NoError(t, err)
+		assert.Equal(t, "test plugin", response.Usage)
+	}
+}
+
+func TestServer_AsExternalPlugin_ServesSession(t *testing.T) {
+	plugin := helperPlugin(t)
+	ctx := context.Background()
+
+	protocol, err := comms.Handshake(ctx, plugin, execute.NewOsExecutor())
+	require.NoError(t, err)
+	assert.False(t, protocol.Legacy)
+	assert.True(t, protocol.Supports(comms.CapabilityServe))
+
+	session, err := comms.StartSession(ctx, plugin, execute.NewOsExecutor(), comms.WithProtocol(protocol))
+	require.NoError(t, err)
+	defer session.Close()
+	require.True(t, session.Serving())
+
+	response, err := comms.CallSession[api_v1.PluginResponse](ctx, session, &api_v1.PluginRequest{Command: api_v1.CommandRun, Args: []string{"x"}})
+	require.NoError(t, err)
+	assert.Equal(t, "ran x", response.Result)
+}
+
+func TestServer_AsExternalPlugin_NegotiatesJSONByDefault(t *testing.T) {
+	protocol, err := comms.Handshake(context.Background(), helperPlugin(t), execute.NewOsExecutor())
+	require.NoError(t, err)
+	assert.Equal(t, marshal.JSON, protocol.Encoding)
+}
+
+func TestServer_AsExternalPlugin_RecordsAndReplaysCBORConversation(t *testing.T) {
+	plugin := helperPlugin(t)
+	ctx := context.Background()
+	golden := filepath.Join(t.TempDir(), "cbor.json")
+	converse := func(e execute.Executor) *usageResponse {
+		protocol, err := comms.Handshake(ctx, plugin, e, comms.WithEncodings(marshal.CBOR))
+		require.NoError(t, err)
+		require.Equal(t, marshal.CBOR, protocol.Encoding)
+		response, err := comms.Call[usageResponse](plugin, &api_v1.PluginRequest{Command: api_v1.CommandUsage}, e, comms.WithProtocol(protocol))
+		require.NoError(t, err)
+		return response
+	}
+
+	recorder := mocks.NewRecordingExecutor(execute.NewOsExecutor())
+	recorded := converse(recorder)
+	require.NoError(t, recorder.Save(golden))
+
+	replayer, err := mocks.LoadReplayExecutor(golden)
+	require.NoError(t, err)
+	assert.Equal(t, recorded, converse(replayer))
+	assert.NoError(t, replayer.Unused())
+}